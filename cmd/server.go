@@ -1,52 +1,268 @@
 package main
 
 import (
+	"context"
 	"fmt"
+	"log"
+	"net"
 	"net/http"
 	"time"
 
 	"github.com/GeovaneCavalcante/rate-limit-api/configs"
+	"github.com/GeovaneCavalcante/rate-limit-api/internal/infra/grpc/envoy"
 	"github.com/GeovaneCavalcante/rate-limit-api/internal/infra/web/handlers"
 	"github.com/GeovaneCavalcante/rate-limit-api/internal/infra/web/middlewares"
 	"github.com/GeovaneCavalcante/rate-limit-api/internal/infra/web/webserver"
 	"github.com/GeovaneCavalcante/rate-limit-api/pkg/logger"
 	"github.com/GeovaneCavalcante/rate-limit-api/pkg/ratelimit"
-	redisEventStorage "github.com/GeovaneCavalcante/rate-limit-api/pkg/ratelimit/redis"
+	memoryStorage "github.com/GeovaneCavalcante/rate-limit-api/pkg/ratelimit/memory"
+	redisStorage "github.com/GeovaneCavalcante/rate-limit-api/pkg/ratelimit/redis"
+	rlsv3 "github.com/envoyproxy/go-control-plane/envoy/service/ratelimit/v3"
 	"github.com/go-redis/redis/v8"
+	"google.golang.org/grpc"
 )
 
+const (
+	storageRedis  = "redis"
+	storageMemory = "memory"
+)
+
+type storages struct {
+	events   ratelimit.EventStorageInterface
+	buckets  ratelimit.TokenBucketStorageInterface
+	counters ratelimit.CounterStorageInterface
+	tats     ratelimit.TATStorageInterface
+	blocks   ratelimit.BlockStorageInterface
+	// sweeper removes the expired keys of the storages without native
+	// expiry, it is nil for redis.
+	sweeper *ratelimit.Sweeper
+}
+
 func main() {
 	configs, err := configs.LoadConfig(".")
 	if err != nil {
 		panic(err)
 	}
 
-	rdb := redis.NewClient(&redis.Options{
-		Addr:     fmt.Sprintf("%s:%s", configs.RedisHost, configs.RedisPort),
-		Password: configs.RedisPassword,
-		DB:       configs.RedisDB,
-	})
-	fmt.Printf("%s:%s", configs.RedisHost, configs.RedisPort)
-
-	redisEventStorage := redisEventStorage.NewRedisEventStorage(rdb)
+	st, err := newStorages(configs)
+	if err != nil {
+		logger.Error("error when creating the RateLimiter storage", err)
+		return
+	}
+	if st.sweeper != nil {
+		go st.sweeper.Run(context.Background())
+	}
 
-	rlIp, err := ratelimit.New(redisEventStorage, "ip", configs.IPConfigLimit.MaxRequests, time.Duration(configs.IPConfigLimit.BlockTimeSecond)*time.Second)
+	rlIp, err := newRateLimiter(configs.Algorithms["ip"], st, "ip", configs.IPConfigLimit.MaxRequests, configs.IPConfigLimit.Interval(), configs.IPConfigLimit.Burst, configs.IPConfigLimit.Block(), middlewares.ToLimits(configs.IPConfigLimit.Limits))
 	if err != nil {
 		logger.Error("error when executing the RateLimiter by ip", err)
 		return
 	}
 
-	rlToken, err := ratelimit.New(redisEventStorage, "token", 0, 0*time.Second)
+	rlToken, err := newRateLimiter(configs.Algorithms["token"], st, "token", 0, 0*time.Second, 0, 0*time.Second, nil)
 	if err != nil {
 		logger.Error("error when executing the RateLimiter by token", err)
 		return
 	}
 
+	rlRoute, err := newRateLimiter(configs.Algorithms["route"], st, "route", 0, 0*time.Second, 0, 0*time.Second, nil)
+	if err != nil {
+		logger.Error("error when executing the RateLimiter by route", err)
+		return
+	}
+
+	var rlTokenIP ratelimit.RateLimiterInterface
+	if l := configs.TokenIPConfigLimit; l != nil {
+		rlTokenIP, err = newRateLimiter(configs.Algorithms["token_ip"], st, "token_ip", l.MaxRequests, l.Interval(), l.Burst, l.Block(), middlewares.ToLimits(l.Limits))
+		if err != nil {
+			logger.Error("error when executing the RateLimiter by token and ip", err)
+			return
+		}
+		if configs.Mode != middlewares.ModeAll {
+			logger.Warn("TOKEN_IP_CONFIG_LIMIT is only enforced with RATE_LIMIT_MODE=all", nil)
+		}
+	}
+
+	routePolicies, err := middlewares.NewRoutePolicies(configs.RoutePolicies)
+	if err != nil {
+		logger.Error("error when parsing the route policies", err)
+		return
+	}
+
+	switch configs.Mode {
+	case "", middlewares.ModeFirst, middlewares.ModeAll:
+	default:
+		logger.Error("error when reading the rate limit mode", fmt.Errorf("unknown rate limit mode %q", configs.Mode))
+		return
+	}
+
+	if configs.AuthDeniedStatus != 0 && (configs.AuthDeniedStatus < 400 || configs.AuthDeniedStatus > 499) {
+		logger.Error("error when reading the auth denied status", fmt.Errorf("auth denied status %d must be a 4xx status", configs.AuthDeniedStatus))
+		return
+	}
+
+	ipResolver, err := middlewares.NewClientIPResolver(configs.TrustedProxies, configs.TrustedProxyHeader)
+	if err != nil {
+		logger.Error("error when parsing the trusted proxies", err)
+		return
+	}
+
+	ipRules, err := middlewares.NewIPRules(configs.IPRules)
+	if err != nil {
+		logger.Error("error when parsing the ip rules", err)
+		return
+	}
+
 	m := middlewares.NewLimiter(rlToken, rlIp, configs.TokensConfigLimit)
+	m.LegacyHeaders = configs.LegacyHeaders
+	m.IPResolver = ipResolver
+	m.IPPrefixes = middlewares.IPPrefixes{IPv4: configs.IPConfigLimit.IPv4Prefix, IPv6: configs.IPConfigLimit.IPv6Prefix}
+	m.IPRules = ipRules
+	m.RouteLimiter = rlRoute
+	m.RoutePolicies = routePolicies
+	if l := configs.TokenIPConfigLimit; l != nil {
+		m.TokenIPLimiter = rlTokenIP
+		m.TokenIPPrefixes = middlewares.IPPrefixes{IPv4: l.IPv4Prefix, IPv6: l.IPv6Prefix}
+	}
+	m.Mode = configs.Mode
+	m.CostHeader = configs.CostHeader
+	m.AuthDeniedStatus = configs.AuthDeniedStatus
+
+	if configs.EnvoyGrpcPort != "" {
+		if err := startEnvoyServer(configs, st, rlIp, rlToken); err != nil {
+			logger.Error("error when starting the envoy rate limit service", err)
+			return
+		}
+	}
+
+	if configs.CheckAPIPort != "" {
+		if configs.CheckAPIPort == configs.WebServerPort {
+			logger.Error("error when starting the check api", fmt.Errorf("CHECK_API_PORT must differ from WEB_SERVER_PORT"))
+			return
+		}
+		check := handlers.NewCheckHandler(rlIp, rlToken, configs.TokensConfigLimit)
+		cs := webserver.New(configs.CheckAPIPort)
+		cs.AddHandler("/v1/check", check.CheckHandler)
+		go cs.Start()
+	}
 
 	ws := webserver.New(configs.WebServerPort)
 	h := handlers.NewHealthHandler()
 
 	ws.AddHandler("/health", m.RateLimiter(http.HandlerFunc(h.HealthHandler)))
+
+	if configs.AuthAPIEnabled {
+		ws.AddHandler("/v1/auth", m.AuthHandler())
+	}
+
+	if len(configs.Upstreams) > 0 {
+		proxy, err := handlers.NewProxyHandler(configs.Upstreams)
+		if err != nil {
+			logger.Error("error when parsing the upstreams", err)
+			return
+		}
+		proxy.IPResolver = ipResolver
+		ws.AddHandler("/", m.RateLimiter(http.HandlerFunc(proxy.ProxyHandler)))
+	}
+
 	ws.Start()
 }
+
+// startEnvoyServer serves the Envoy rate limit service in the background.
+func startEnvoyServer(cfg *configs.Environments, st *storages, rlIp, rlToken ratelimit.RateLimiterInterface) error {
+	rlDescriptor, err := newRateLimiter(cfg.Algorithms["descriptor"], st, "descriptor", 0, 0*time.Second, 0, 0*time.Second, nil)
+	if err != nil {
+		return err
+	}
+
+	descriptors, err := envoy.NewDescriptors(cfg.EnvoyDescriptors)
+	if err != nil {
+		return err
+	}
+
+	lis, err := net.Listen("tcp", cfg.EnvoyGrpcPort)
+	if err != nil {
+		return err
+	}
+
+	server := grpc.NewServer()
+	rlsv3.RegisterRateLimitServiceServer(server, envoy.NewRateLimitService(rlIp, rlToken, rlDescriptor, descriptors, cfg.TokensConfigLimit))
+
+	go func() {
+		log.Println("Starting envoy rate limit service...")
+		if err := server.Serve(lis); err != nil {
+			panic(err)
+		}
+	}()
+
+	return nil
+}
+
+func newStorages(cfg *configs.Environments) (*storages, error) {
+	switch cfg.Storage {
+	case "", storageRedis:
+		rdb := redis.NewClient(&redis.Options{
+			Addr:     fmt.Sprintf("%s:%s", cfg.RedisHost, cfg.RedisPort),
+			Password: cfg.RedisPassword,
+			DB:       cfg.RedisDB,
+		})
+		fmt.Printf("%s:%s", cfg.RedisHost, cfg.RedisPort)
+
+		return &storages{
+			events:   redisStorage.NewRedisEventStorage(rdb),
+			buckets:  redisStorage.NewRedisTokenBucketStorage(rdb),
+			counters: redisStorage.NewRedisCounterStorage(rdb),
+			tats:     redisStorage.NewRedisTATStorage(rdb),
+			blocks:   redisStorage.NewRedisBlockStorage(rdb),
+		}, nil
+	case storageMemory:
+		events := memoryStorage.NewMemoryEventStorage(cfg.MemoryMaxKeys)
+		buckets := memoryStorage.NewMemoryTokenBucketStorage(cfg.MemoryMaxKeys)
+		counters := memoryStorage.NewMemoryCounterStorage(cfg.MemoryMaxKeys)
+		tats := memoryStorage.NewMemoryTATStorage(cfg.MemoryMaxKeys)
+		blocks := memoryStorage.NewMemoryBlockStorage(cfg.MemoryMaxKeys)
+
+		sweeper := ratelimit.NewSweeper(time.Duration(cfg.MemorySweepIntervalSecond)*time.Second, events, buckets, counters, tats, blocks)
+		sweeper.OnError = func(err error) {
+			logger.Error("error when sweeping the memory storage", err)
+		}
+
+		return &storages{
+			events:   events,
+			buckets:  buckets,
+			counters: counters,
+			tats:     tats,
+			blocks:   blocks,
+			sweeper:  sweeper,
+		}, nil
+	}
+	return nil, fmt.Errorf("unknown rate limit storage %q", cfg.Storage)
+}
+
+func newRateLimiter(algorithm string, st *storages, ns string, max int64, inter time.Duration, burst int64, block time.Duration, limits []ratelimit.Limit) (ratelimit.RateLimiterInterface, error) {
+	var rl ratelimit.RateLimiterInterface
+	var err error
+
+	switch algorithm {
+	case "", ratelimit.AlgorithmSlidingLog:
+		rl, err = ratelimit.New(st.events, ns, max, inter)
+	case ratelimit.AlgorithmTokenBucket:
+		rl, err = ratelimit.NewTokenBucketLimiter(st.buckets, ns, max, inter, burst)
+	case ratelimit.AlgorithmSlidingWindow:
+		rl, err = ratelimit.NewSlidingWindowLimiter(st.counters, ns, max, inter)
+	case ratelimit.AlgorithmGCRA:
+		rl, err = ratelimit.NewGCRALimiter(st.tats, ns, max, inter, burst)
+	default:
+		return nil, fmt.Errorf("unknown rate limit algorithm %q for namespace %s", algorithm, ns)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	rl, err = ratelimit.NewMultiLimiter(rl, limits)
+	if err != nil {
+		return nil, err
+	}
+
+	return ratelimit.NewBlockingLimiter(rl, st.blocks, ns, max, inter, block)
+}
@@ -2,19 +2,139 @@ package configs
 
 import (
 	"encoding/json"
+	"time"
 
 	"github.com/spf13/viper"
 )
 
+// Limit is one of the windows of a policy with several limits.
+type Limit struct {
+	MaxRequests    int64    `json:"max_requests"`
+	WindowSecond   int64    `json:"window_seconds"`
+	WindowDuration Duration `json:"window"`
+	Burst          int64    `json:"burst"`
+}
+
+func (l Limit) Interval() time.Duration {
+	return chooseDuration(l.WindowDuration, l.WindowSecond)
+}
+
 type TokenConfigLimit struct {
-	Token           string `json:"token"`
-	MaxRequests     int64  `json:"max_requests"`
-	BlockTimeSecond int64  `json:"block_time_seconds"`
+	Token       string `json:"token"`
+	MaxRequests int64  `json:"max_requests"`
+	// BlockTimeSecond is the deprecated name of WindowSecond, it is only
+	// used when WindowSecond is not set.
+	BlockTimeSecond     int64 `json:"block_time_seconds"`
+	WindowSecond        int64 `json:"window_seconds"`
+	BlockDurationSecond int64 `json:"block_duration_seconds"`
+	// WindowDuration and BlockDuration accept sub-second values such as
+	// "250ms" and take precedence over the *_seconds fields.
+	WindowDuration Duration `json:"window"`
+	BlockDuration  Duration `json:"block_duration"`
+	Burst          int64    `json:"burst"`
+	// Limits are several windows enforced together, such as a per second
+	// burst and a daily quota, in place of max_requests and window.
+	Limits []Limit `json:"limits"`
+}
+
+func (t TokenConfigLimit) Interval() time.Duration {
+	return chooseDuration(t.WindowDuration, windowOrBlockTime(t.WindowSecond, t.BlockTimeSecond))
+}
+
+func (t TokenConfigLimit) Block() time.Duration {
+	return chooseDuration(t.BlockDuration, t.BlockDurationSecond)
 }
 
 type IPConfigLimit struct {
-	MaxRequests     int64 `json:"max_requests"`
-	BlockTimeSecond int64 `json:"block_time_seconds"`
+	MaxRequests int64 `json:"max_requests"`
+	// BlockTimeSecond is the deprecated name of WindowSecond, it is only
+	// used when WindowSecond is not set.
+	BlockTimeSecond     int64 `json:"block_time_seconds"`
+	WindowSecond        int64 `json:"window_seconds"`
+	BlockDurationSecond int64 `json:"block_duration_seconds"`
+	// WindowDuration and BlockDuration accept sub-second values such as
+	// "250ms" and take precedence over the *_seconds fields.
+	WindowDuration Duration `json:"window"`
+	BlockDuration  Duration `json:"block_duration"`
+	Burst          int64    `json:"burst"`
+	// IPv4Prefix and IPv6Prefix group the addresses in networks of this
+	// size, such as 64 for IPv6. Zero limits each address on its own.
+	IPv4Prefix int `json:"ipv4_prefix"`
+	IPv6Prefix int `json:"ipv6_prefix"`
+	// Limits are several windows enforced together, such as a per second
+	// burst and a daily quota, in place of max_requests and window.
+	Limits []Limit `json:"limits"`
+}
+
+func (i IPConfigLimit) Interval() time.Duration {
+	return chooseDuration(i.WindowDuration, windowOrBlockTime(i.WindowSecond, i.BlockTimeSecond))
+}
+
+func (i IPConfigLimit) Block() time.Duration {
+	return chooseDuration(i.BlockDuration, i.BlockDurationSecond)
+}
+
+// IPRule applies an action to the clients of the given CIDRs: "allow"
+// exempts them from the limits, "deny" rejects them and "limit" applies the
+// embedded limit instead of IP_CONFIG_LIMIT.
+type IPRule struct {
+	CIDRs  []string `json:"cidrs"`
+	Action string   `json:"action"`
+	IPConfigLimit
+}
+
+// RoutePolicy gives the requests matching the method and path pattern their
+// own limit. Method is optional and path segments written as "*" or
+// "{name}" match any segment, a path ending in "/" matches the whole subtree.
+type RoutePolicy struct {
+	Method string `json:"method"`
+	Path   string `json:"path"`
+	// Cost is the number of units each request of the route consumes, a
+	// policy may set only the cost and keep the token and ip limits.
+	Cost int64 `json:"cost"`
+	IPConfigLimit
+}
+
+// Upstream is a service the gateway forwards the allowed requests to. The
+// requests whose path starts with Prefix are sent to URL, the longest
+// prefix wins.
+type Upstream struct {
+	Prefix string `json:"prefix"`
+	URL    string `json:"url"`
+	// StripPrefix removes the prefix from the path sent to the upstream.
+	StripPrefix bool `json:"strip_prefix"`
+	// PreserveHost keeps the Host header of the client instead of the host
+	// of URL.
+	PreserveHost  bool              `json:"preserve_host"`
+	SetHeaders    map[string]string `json:"set_headers"`
+	RemoveHeaders []string          `json:"remove_headers"`
+	// Timeout is how long to wait for the response headers of the upstream,
+	// zero waits forever.
+	Timeout Duration `json:"timeout"`
+}
+
+// EnvoyDescriptor maps the Envoy rate limit descriptors whose first entry
+// has Key, and Value when it is set, to a namespace: "ip" and "token" share
+// the buckets of the HTTP limiters, "descriptor" uses the embedded limit.
+type EnvoyDescriptor struct {
+	Key       string `json:"key"`
+	Value     string `json:"value"`
+	NameSpace string `json:"namespace"`
+	IPConfigLimit
+}
+
+func windowOrBlockTime(window, blockTime int64) int64 {
+	if window != 0 {
+		return window
+	}
+	return blockTime
+}
+
+func chooseDuration(d Duration, seconds int64) time.Duration {
+	if d != 0 {
+		return time.Duration(d)
+	}
+	return time.Duration(seconds) * time.Second
 }
 
 var (
@@ -22,15 +142,40 @@ var (
 )
 
 type Environments struct {
-	WebServerPort         string `mapstructure:"WEB_SERVER_PORT"`
-	TokensConfigLimitJson string `mapstructure:"TOKENS_CONFIG_LIMIT"`
-	TokensConfigLimit     []TokenConfigLimit
-	IPConfigLimitJson     string `mapstructure:"IP_CONFIG_LIMIT"`
-	IPConfigLimit         IPConfigLimit
-	RedisHost             string `mapstructure:"REDIS_HOST"`
-	RedisPort             string `mapstructure:"REDIS_PORT"`
-	RedisPassword         string `mapstructure:"REDIS_PASSWORD"`
-	RedisDB               int    `mapstructure:"REDIS_DB"`
+	WebServerPort             string `mapstructure:"WEB_SERVER_PORT"`
+	TokensConfigLimitJson     string `mapstructure:"TOKENS_CONFIG_LIMIT"`
+	TokensConfigLimit         []TokenConfigLimit
+	IPConfigLimitJson         string `mapstructure:"IP_CONFIG_LIMIT"`
+	IPConfigLimit             IPConfigLimit
+	TokenIPConfigLimitJson    string `mapstructure:"TOKEN_IP_CONFIG_LIMIT"`
+	TokenIPConfigLimit        *IPConfigLimit
+	RedisHost                 string `mapstructure:"REDIS_HOST"`
+	RedisPort                 string `mapstructure:"REDIS_PORT"`
+	RedisPassword             string `mapstructure:"REDIS_PASSWORD"`
+	RedisDB                   int    `mapstructure:"REDIS_DB"`
+	Storage                   string `mapstructure:"RATE_LIMIT_STORAGE"`
+	MemoryMaxKeys             int    `mapstructure:"MEMORY_MAX_KEYS"`
+	MemorySweepIntervalSecond int64  `mapstructure:"MEMORY_SWEEP_INTERVAL_SECONDS"`
+	AlgorithmsJson            string `mapstructure:"RATE_LIMIT_ALGORITHMS"`
+	Algorithms                map[string]string
+	LegacyHeaders             bool   `mapstructure:"RATE_LIMIT_LEGACY_HEADERS"`
+	TrustedProxiesJson        string `mapstructure:"TRUSTED_PROXIES"`
+	TrustedProxies            []string
+	TrustedProxyHeader        string `mapstructure:"TRUSTED_PROXY_HEADER"`
+	IPRulesJson               string `mapstructure:"IP_RULES"`
+	IPRules                   []IPRule
+	RoutePoliciesJson         string `mapstructure:"ROUTE_POLICIES"`
+	Mode                      string `mapstructure:"RATE_LIMIT_MODE"`
+	CostHeader                string `mapstructure:"RATE_LIMIT_COST_HEADER"`
+	RoutePolicies             []RoutePolicy
+	UpstreamsJson             string `mapstructure:"UPSTREAMS"`
+	Upstreams                 []Upstream
+	CheckAPIPort              string `mapstructure:"CHECK_API_PORT"`
+	AuthAPIEnabled            bool   `mapstructure:"AUTH_API_ENABLED"`
+	AuthDeniedStatus          int    `mapstructure:"AUTH_DENIED_STATUS"`
+	EnvoyGrpcPort             string `mapstructure:"ENVOY_GRPC_PORT"`
+	EnvoyDescriptorsJson      string `mapstructure:"ENVOY_DESCRIPTORS"`
+	EnvoyDescriptors          []EnvoyDescriptor
 }
 
 func LoadConfig(path string) (*Environments, error) {
@@ -60,6 +205,55 @@ func LoadConfig(path string) (*Environments, error) {
 		return nil, err
 	}
 
+	if envVars.TokenIPConfigLimitJson != "" {
+		err = json.Unmarshal([]byte(envVars.TokenIPConfigLimitJson), &envVars.TokenIPConfigLimit)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if envVars.AlgorithmsJson != "" {
+		err = json.Unmarshal([]byte(envVars.AlgorithmsJson), &envVars.Algorithms)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if envVars.TrustedProxiesJson != "" {
+		err = json.Unmarshal([]byte(envVars.TrustedProxiesJson), &envVars.TrustedProxies)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if envVars.IPRulesJson != "" {
+		err = json.Unmarshal([]byte(envVars.IPRulesJson), &envVars.IPRules)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if envVars.RoutePoliciesJson != "" {
+		err = json.Unmarshal([]byte(envVars.RoutePoliciesJson), &envVars.RoutePolicies)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if envVars.UpstreamsJson != "" {
+		err = json.Unmarshal([]byte(envVars.UpstreamsJson), &envVars.Upstreams)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if envVars.EnvoyDescriptorsJson != "" {
+		err = json.Unmarshal([]byte(envVars.EnvoyDescriptorsJson), &envVars.EnvoyDescriptors)
+		if err != nil {
+			return nil, err
+		}
+	}
+
 	return envVars, err
 }
 
@@ -0,0 +1,260 @@
+package envoy
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/GeovaneCavalcante/rate-limit-api/configs"
+	"github.com/GeovaneCavalcante/rate-limit-api/internal/infra/web/middlewares"
+	"github.com/GeovaneCavalcante/rate-limit-api/pkg/logger"
+	"github.com/GeovaneCavalcante/rate-limit-api/pkg/ratelimit"
+	corev3 "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
+	ratelimitv3 "github.com/envoyproxy/go-control-plane/envoy/extensions/common/ratelimit/v3"
+	rlsv3 "github.com/envoyproxy/go-control-plane/envoy/service/ratelimit/v3"
+	typev3 "github.com/envoyproxy/go-control-plane/envoy/type/v3"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/durationpb"
+)
+
+const (
+	NameSpaceIP         = "ip"
+	NameSpaceToken      = "token"
+	NameSpaceDescriptor = "descriptor"
+)
+
+// Descriptor maps the Envoy descriptors whose first entry has Key, and Value
+// when it is set, to the limiter of NameSpace.
+type Descriptor struct {
+	Key       string
+	Value     string
+	NameSpace string
+	// Options is the limit of the "descriptor" namespace, the "ip" one may
+	// also set its own.
+	Options *ratelimit.Options
+}
+
+func NewDescriptors(descriptors []configs.EnvoyDescriptor) ([]Descriptor, error) {
+	result := make([]Descriptor, 0, len(descriptors))
+
+	for i, d := range descriptors {
+		if d.Key == "" {
+			return nil, fmt.Errorf("envoy descriptor %d must have a key", i)
+		}
+
+		descriptor := Descriptor{Key: d.Key, Value: d.Value, NameSpace: d.NameSpace}
+		if descriptor.NameSpace == "" {
+			descriptor.NameSpace = NameSpaceDescriptor
+		}
+
+		hasLimit := len(d.Limits) > 0 || d.MaxRequests > 0
+		switch {
+		case descriptor.NameSpace != NameSpaceIP && descriptor.NameSpace != NameSpaceToken && descriptor.NameSpace != NameSpaceDescriptor:
+			return nil, fmt.Errorf("envoy descriptor %d has an unknown namespace %q", i, d.NameSpace)
+		case hasLimit && len(d.Limits) == 0 && d.Interval() <= 0, !hasLimit && descriptor.NameSpace == NameSpaceDescriptor:
+			return nil, fmt.Errorf("envoy descriptor %d must set max_requests and window, or limits", i)
+		case hasLimit:
+			descriptor.Options = &ratelimit.Options{
+				MaxInInterval: d.MaxRequests,
+				Interval:      d.Interval(),
+				Burst:         d.Burst,
+				BlockDuration: d.Block(),
+				Limits:        middlewares.ToLimits(d.Limits),
+			}
+		}
+
+		result = append(result, descriptor)
+	}
+
+	return result, nil
+}
+
+// RateLimitService implements the Envoy rate limit service on top of the
+// limiters, so Envoy can share the buckets of the HTTP server. Descriptors
+// that match none of the Descriptors are not limited.
+type RateLimitService struct {
+	rlsv3.UnimplementedRateLimitServiceServer
+	Limiters          map[string]ratelimit.RateLimiterInterface
+	Descriptors       []Descriptor
+	TokensConfigLimit []configs.TokenConfigLimit
+}
+
+func NewRateLimitService(ipLimiter, tokenLimiter, descriptorLimiter ratelimit.RateLimiterInterface, descriptors []Descriptor, tk []configs.TokenConfigLimit) *RateLimitService {
+	return &RateLimitService{
+		Limiters: map[string]ratelimit.RateLimiterInterface{
+			NameSpaceIP:         ipLimiter,
+			NameSpaceToken:      tokenLimiter,
+			NameSpaceDescriptor: descriptorLimiter,
+		},
+		Descriptors:       descriptors,
+		TokensConfigLimit: tk,
+	}
+}
+
+func (s *RateLimitService) ShouldRateLimit(ctx context.Context, req *rlsv3.RateLimitRequest) (*rlsv3.RateLimitResponse, error) {
+	if req.GetDomain() == "" {
+		return nil, status.Error(codes.InvalidArgument, "domain must not be empty")
+	}
+	if req.GetHitsAddend() > ratelimit.MaxCost {
+		return nil, status.Errorf(codes.InvalidArgument, "hits_addend must not exceed %d", ratelimit.MaxCost)
+	}
+
+	resp := &rlsv3.RateLimitResponse{OverallCode: rlsv3.RateLimitResponse_OK}
+	decisions := make([]*ratelimit.Decision, 0, len(req.GetDescriptors()))
+
+	for _, d := range req.GetDescriptors() {
+		descriptorStatus, decision, err := s.decide(ctx, req, d)
+		if err != nil {
+			return nil, err
+		}
+		if descriptorStatus.Code == rlsv3.RateLimitResponse_OVER_LIMIT {
+			resp.OverallCode = rlsv3.RateLimitResponse_OVER_LIMIT
+		}
+		resp.Statuses = append(resp.Statuses, descriptorStatus)
+		decisions = append(decisions, decision)
+	}
+
+	if decision := ratelimit.MostRestrictive(decisions...); decision != nil {
+		resp.ResponseHeadersToAdd = headerValues(decision)
+	}
+
+	return resp, nil
+}
+
+func (s *RateLimitService) decide(ctx context.Context, req *rlsv3.RateLimitRequest, d *ratelimitv3.RateLimitDescriptor) (*rlsv3.RateLimitResponse_DescriptorStatus, *ratelimit.Decision, error) {
+	ok := &rlsv3.RateLimitResponse_DescriptorStatus{Code: rlsv3.RateLimitResponse_OK}
+
+	entries := d.GetEntries()
+	if len(entries) == 0 {
+		return ok, nil, nil
+	}
+	descriptor := s.match(entries[0])
+	if descriptor == nil {
+		return ok, nil, nil
+	}
+
+	opt := descriptor.Options
+	key := entriesKey(entries[0].GetValue(), entries[1:])
+	switch descriptor.NameSpace {
+	case NameSpaceToken:
+		opt = middlewares.TokenOptions(s.TokensConfigLimit, entries[0].GetValue())
+		if opt == nil {
+			logger.Error(fmt.Sprintf("token %s not found", entries[0].GetValue()), nil)
+			return &rlsv3.RateLimitResponse_DescriptorStatus{Code: rlsv3.RateLimitResponse_OVER_LIMIT}, nil, nil
+		}
+	case NameSpaceDescriptor:
+		key = entriesKey(req.GetDomain(), entries)
+	}
+
+	if override := d.GetLimit(); override != nil && unitDuration(override.GetUnit()) > 0 {
+		overridden := ratelimit.Options{}
+		if opt != nil {
+			overridden = *opt
+		}
+		overridden.MaxInInterval = int64(override.GetRequestsPerUnit())
+		overridden.Interval = unitDuration(override.GetUnit())
+		overridden.Limits = nil
+		opt = &overridden
+	}
+	opt = middlewares.WithCost(opt, int64(req.GetHitsAddend()))
+
+	decision, err := s.Limiters[descriptor.NameSpace].Decide(ctx, key, opt)
+	if err != nil {
+		logger.Error(fmt.Sprintf("error when executing the RateLimiter by %s", descriptor.NameSpace), err)
+		return nil, nil, status.Error(codes.Unavailable, "error when executing the RateLimiter")
+	}
+	decision.Rule = descriptor.Key
+
+	descriptorStatus := &rlsv3.RateLimitResponse_DescriptorStatus{
+		Code: rlsv3.RateLimitResponse_OK,
+		CurrentLimit: &rlsv3.RateLimitResponse_RateLimit{
+			Name:            descriptor.Key,
+			RequestsPerUnit: uint32(decision.Limit),
+			Unit:            windowUnit(decision.Window),
+		},
+		LimitRemaining:     uint32(max(0, decision.Remaining)),
+		DurationUntilReset: durationpb.New(max(0, time.Until(decision.ResetAt))),
+	}
+	if !decision.Allowed {
+		descriptorStatus.Code = rlsv3.RateLimitResponse_OVER_LIMIT
+	}
+
+	return descriptorStatus, decision, nil
+}
+
+func (s *RateLimitService) match(entry *ratelimitv3.RateLimitDescriptor_Entry) *Descriptor {
+	for i := range s.Descriptors {
+		d := &s.Descriptors[i]
+		if d.Key == entry.GetKey() && (d.Value == "" || d.Value == entry.GetValue()) {
+			return d
+		}
+	}
+	return nil
+}
+
+// entriesKey appends the entries to the key, such as
+// "api:generic_key=checkout:tenant=acme".
+func entriesKey(key string, entries []*ratelimitv3.RateLimitDescriptor_Entry) string {
+	var b strings.Builder
+	b.WriteString(key)
+	for _, e := range entries {
+		fmt.Fprintf(&b, ":%s=%s", e.GetKey(), e.GetValue())
+	}
+	return b.String()
+}
+
+// headerValues are the RateLimit headers Envoy adds to the response.
+func headerValues(d *ratelimit.Decision) []*corev3.HeaderValue {
+	h := http.Header{}
+	middlewares.SetRateLimitHeaders(h, d)
+
+	names := make([]string, 0, len(h))
+	for name := range h {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	values := make([]*corev3.HeaderValue, 0, len(names))
+	for _, name := range names {
+		values = append(values, &corev3.HeaderValue{Key: name, Value: h.Get(name)})
+	}
+	return values
+}
+
+var units = []struct {
+	unit     rlsv3.RateLimitResponse_RateLimit_Unit
+	duration time.Duration
+}{
+	{rlsv3.RateLimitResponse_RateLimit_SECOND, time.Second},
+	{rlsv3.RateLimitResponse_RateLimit_MINUTE, time.Minute},
+	{rlsv3.RateLimitResponse_RateLimit_HOUR, time.Hour},
+	{rlsv3.RateLimitResponse_RateLimit_DAY, 24 * time.Hour},
+	{rlsv3.RateLimitResponse_RateLimit_MONTH, 30 * 24 * time.Hour},
+	{rlsv3.RateLimitResponse_RateLimit_YEAR, 365 * 24 * time.Hour},
+}
+
+// unitDuration converts the unit of a descriptor limit override, both enums
+// share their values.
+func unitDuration(unit typev3.RateLimitUnit) time.Duration {
+	for _, u := range units {
+		if int32(u.unit) == int32(unit) {
+			return u.duration
+		}
+	}
+	return 0
+}
+
+// windowUnit is the unit of the window, or UNKNOWN when the window is not
+// one of them.
+func windowUnit(window time.Duration) rlsv3.RateLimitResponse_RateLimit_Unit {
+	for _, u := range units {
+		if u.duration == window {
+			return u.unit
+		}
+	}
+	return rlsv3.RateLimitResponse_RateLimit_UNKNOWN
+}
@@ -0,0 +1,189 @@
+package envoy
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/GeovaneCavalcante/rate-limit-api/configs"
+	"github.com/GeovaneCavalcante/rate-limit-api/pkg/ratelimit"
+	mock_ratelimit "github.com/GeovaneCavalcante/rate-limit-api/pkg/ratelimit/mock"
+	ratelimitv3 "github.com/envoyproxy/go-control-plane/envoy/extensions/common/ratelimit/v3"
+	rlsv3 "github.com/envoyproxy/go-control-plane/envoy/service/ratelimit/v3"
+	typev3 "github.com/envoyproxy/go-control-plane/envoy/type/v3"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/mock/gomock"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+func newClient(t *testing.T, service rlsv3.RateLimitServiceServer) rlsv3.RateLimitServiceClient {
+	listener := bufconn.Listen(1 << 20)
+	server := grpc.NewServer()
+	rlsv3.RegisterRateLimitServiceServer(server, service)
+	go server.Serve(listener)
+	t.Cleanup(server.Stop)
+
+	conn, err := grpc.Dial("bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return listener.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	return rlsv3.NewRateLimitServiceClient(conn)
+}
+
+func descriptor(entries ...string) *ratelimitv3.RateLimitDescriptor {
+	d := &ratelimitv3.RateLimitDescriptor{}
+	for i := 0; i < len(entries); i += 2 {
+		d.Entries = append(d.Entries, &ratelimitv3.RateLimitDescriptor_Entry{Key: entries[i], Value: entries[i+1]})
+	}
+	return d
+}
+
+func TestShouldRateLimit(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	ipLimiter := mock_ratelimit.NewMockRateLimiterInterface(ctrl)
+	tokenLimiter := mock_ratelimit.NewMockRateLimiterInterface(ctrl)
+	descriptorLimiter := mock_ratelimit.NewMockRateLimiterInterface(ctrl)
+
+	descriptors, err := NewDescriptors([]configs.EnvoyDescriptor{
+		{Key: "remote_address", NameSpace: "ip"},
+		{Key: "api_key", NameSpace: "token"},
+		{Key: "generic_key", Value: "checkout", IPConfigLimit: configs.IPConfigLimit{MaxRequests: 10, WindowSecond: 60}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	client := newClient(t, NewRateLimitService(ipLimiter, tokenLimiter, descriptorLimiter, descriptors, []configs.TokenConfigLimit{
+		{Token: "123", MaxRequests: 100, WindowSecond: 60},
+	}))
+	ctx := context.Background()
+
+	t.Run("should share the bucket of the ip limiter", func(t *testing.T) {
+		ipLimiter.EXPECT().Decide(gomock.Any(), "198.51.100.9", nil).Return(&ratelimit.Decision{
+			Allowed:   true,
+			Limit:     20,
+			Remaining: 19,
+			Window:    time.Minute,
+			ResetAt:   time.Now().Add(time.Minute),
+		}, nil)
+
+		resp, err := client.ShouldRateLimit(ctx, &rlsv3.RateLimitRequest{
+			Domain:      "edge",
+			Descriptors: []*ratelimitv3.RateLimitDescriptor{descriptor("remote_address", "198.51.100.9")},
+		})
+		if assert.NoError(t, err) {
+			assert.Equal(t, rlsv3.RateLimitResponse_OK, resp.OverallCode)
+			assert.Equal(t, uint32(20), resp.Statuses[0].CurrentLimit.RequestsPerUnit)
+			assert.Equal(t, rlsv3.RateLimitResponse_RateLimit_MINUTE, resp.Statuses[0].CurrentLimit.Unit)
+			assert.Equal(t, uint32(19), resp.Statuses[0].LimitRemaining)
+			assert.Equal(t, "Ratelimit-Limit", resp.ResponseHeadersToAdd[0].Key)
+			assert.Equal(t, "20", resp.ResponseHeadersToAdd[0].Value)
+		}
+	})
+
+	t.Run("should report every descriptor and reject when any is over the limit", func(t *testing.T) {
+		tokenOptions := &ratelimit.Options{NameSpace: "token", MaxInInterval: 100, Interval: time.Minute, Cost: 2}
+		tokenLimiter.EXPECT().Decide(gomock.Any(), "123", tokenOptions).Return(&ratelimit.Decision{Allowed: true, Limit: 100, Remaining: 98}, nil)
+
+		descriptorOptions := &ratelimit.Options{MaxInInterval: 10, Interval: time.Minute, Cost: 2}
+		descriptorLimiter.EXPECT().Decide(gomock.Any(), "edge:generic_key=checkout:tenant=acme", descriptorOptions).Return(&ratelimit.Decision{
+			Allowed:    false,
+			Limit:      10,
+			RetryAfter: 30 * time.Second,
+		}, nil)
+
+		resp, err := client.ShouldRateLimit(ctx, &rlsv3.RateLimitRequest{
+			Domain: "edge",
+			Descriptors: []*ratelimitv3.RateLimitDescriptor{
+				descriptor("api_key", "123"),
+				descriptor("generic_key", "checkout", "tenant", "acme"),
+				descriptor("generic_key", "search"),
+			},
+			HitsAddend: 2,
+		})
+		if assert.NoError(t, err) {
+			assert.Equal(t, rlsv3.RateLimitResponse_OVER_LIMIT, resp.OverallCode)
+			assert.Equal(t, rlsv3.RateLimitResponse_OK, resp.Statuses[0].Code)
+			assert.Equal(t, rlsv3.RateLimitResponse_OVER_LIMIT, resp.Statuses[1].Code)
+			assert.Equal(t, rlsv3.RateLimitResponse_OK, resp.Statuses[2].Code)
+			assert.Nil(t, resp.Statuses[2].CurrentLimit)
+
+			headers := map[string]string{}
+			for _, h := range resp.ResponseHeadersToAdd {
+				headers[h.Key] = h.Value
+			}
+			assert.Equal(t, "30", headers["Retry-After"])
+		}
+	})
+
+	t.Run("should apply the limit override of the descriptor", func(t *testing.T) {
+		options := &ratelimit.Options{MaxInInterval: 5, Interval: time.Second}
+		ipLimiter.EXPECT().Decide(gomock.Any(), "198.51.100.9:path=/login", options).Return(&ratelimit.Decision{Allowed: true, Limit: 5, Window: time.Second}, nil)
+
+		d := descriptor("remote_address", "198.51.100.9", "path", "/login")
+		d.Limit = &ratelimitv3.RateLimitDescriptor_RateLimitOverride{RequestsPerUnit: 5, Unit: typev3.RateLimitUnit_SECOND}
+
+		resp, err := client.ShouldRateLimit(ctx, &rlsv3.RateLimitRequest{Domain: "edge", Descriptors: []*ratelimitv3.RateLimitDescriptor{d}})
+		if assert.NoError(t, err) {
+			assert.Equal(t, rlsv3.RateLimitResponse_OK, resp.OverallCode)
+			assert.Equal(t, rlsv3.RateLimitResponse_RateLimit_SECOND, resp.Statuses[0].CurrentLimit.Unit)
+		}
+	})
+
+	t.Run("should reject an unknown token", func(t *testing.T) {
+		resp, err := client.ShouldRateLimit(ctx, &rlsv3.RateLimitRequest{
+			Domain:      "edge",
+			Descriptors: []*ratelimitv3.RateLimitDescriptor{descriptor("api_key", "unknown")},
+		})
+		if assert.NoError(t, err) {
+			assert.Equal(t, rlsv3.RateLimitResponse_OVER_LIMIT, resp.OverallCode)
+		}
+	})
+
+	t.Run("should return an error when the limiter fails", func(t *testing.T) {
+		ipLimiter.EXPECT().Decide(gomock.Any(), "198.51.100.9", nil).Return(nil, assert.AnError)
+
+		_, err := client.ShouldRateLimit(ctx, &rlsv3.RateLimitRequest{
+			Domain:      "edge",
+			Descriptors: []*ratelimitv3.RateLimitDescriptor{descriptor("remote_address", "198.51.100.9")},
+		})
+		assert.Equal(t, codes.Unavailable, status.Code(err))
+	})
+
+	t.Run("should require the domain", func(t *testing.T) {
+		_, err := client.ShouldRateLimit(ctx, &rlsv3.RateLimitRequest{})
+		assert.Equal(t, codes.InvalidArgument, status.Code(err))
+	})
+
+	t.Run("should reject hits above the maximum cost", func(t *testing.T) {
+		_, err := client.ShouldRateLimit(ctx, &rlsv3.RateLimitRequest{
+			Domain:      "edge",
+			Descriptors: []*ratelimitv3.RateLimitDescriptor{descriptor("remote_address", "198.51.100.9")},
+			HitsAddend:  ratelimit.MaxCost + 1,
+		})
+		assert.Equal(t, codes.InvalidArgument, status.Code(err))
+	})
+}
+
+func TestNewDescriptors(t *testing.T) {
+	_, err := NewDescriptors([]configs.EnvoyDescriptor{{NameSpace: "ip"}})
+	assert.ErrorContains(t, err, "must have a key")
+
+	_, err = NewDescriptors([]configs.EnvoyDescriptor{{Key: "remote_address", NameSpace: "route"}})
+	assert.ErrorContains(t, err, "unknown namespace")
+
+	_, err = NewDescriptors([]configs.EnvoyDescriptor{{Key: "generic_key"}})
+	assert.ErrorContains(t, err, "must set max_requests and window")
+}
@@ -0,0 +1,135 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/GeovaneCavalcante/rate-limit-api/configs"
+	"github.com/GeovaneCavalcante/rate-limit-api/internal/infra/web/middlewares"
+	"github.com/GeovaneCavalcante/rate-limit-api/pkg/logger"
+	"github.com/GeovaneCavalcante/rate-limit-api/pkg/ratelimit"
+)
+
+const maxCheckBodySize = 1 << 20
+
+// CheckRequest asks for a decision on Key in NameSpace, "ip" or "token".
+// Descriptors, such as {"route": "/login", "tenant": "acme"}, split the key
+// in separate buckets.
+type CheckRequest struct {
+	NameSpace   string            `json:"namespace"`
+	Key         string            `json:"key"`
+	Cost        int64             `json:"cost"`
+	Descriptors map[string]string `json:"descriptors"`
+}
+
+// CheckResponse is the ratelimit.Decision with the durations in
+// milliseconds.
+type CheckResponse struct {
+	Allowed      bool      `json:"allowed"`
+	NameSpace    string    `json:"namespace"`
+	Key          string    `json:"key"`
+	Limit        int64     `json:"limit"`
+	Remaining    int64     `json:"remaining"`
+	WindowMs     int64     `json:"window_ms"`
+	ResetAt      time.Time `json:"reset_at"`
+	ResetAfterMs int64     `json:"reset_after_ms"`
+	RetryAfterMs int64     `json:"retry_after_ms"`
+}
+
+// CheckHandler lets other services consult the limiters without sending
+// their traffic through the server. Each call consumes the cost from the
+// bucket when it is allowed.
+type CheckHandler struct {
+	Limiters          map[string]ratelimit.RateLimiterInterface
+	TokensConfigLimit []configs.TokenConfigLimit
+}
+
+func NewCheckHandler(ipLimiter, tokenLimiter ratelimit.RateLimiterInterface, tk []configs.TokenConfigLimit) *CheckHandler {
+	return &CheckHandler{
+		Limiters: map[string]ratelimit.RateLimiterInterface{
+			"ip":    ipLimiter,
+			"token": tokenLimiter,
+		},
+		TokensConfigLimit: tk,
+	}
+}
+
+func (h *CheckHandler) CheckHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	var req CheckRequest
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxCheckBodySize)).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+
+	limiter, ok := h.Limiters[req.NameSpace]
+	switch {
+	case !ok:
+		writeError(w, http.StatusBadRequest, fmt.Sprintf("unknown namespace %q", req.NameSpace))
+		return
+	case req.Key == "":
+		writeError(w, http.StatusBadRequest, "key is required")
+		return
+	case req.Cost < 0 || req.Cost > ratelimit.MaxCost:
+		writeError(w, http.StatusBadRequest, fmt.Sprintf("cost must be between 0 and %d", ratelimit.MaxCost))
+		return
+	}
+
+	var opt *ratelimit.Options
+	if req.NameSpace == "token" {
+		opt = middlewares.TokenOptions(h.TokensConfigLimit, req.Key)
+		if opt == nil {
+			writeError(w, http.StatusNotFound, "token not found")
+			return
+		}
+	}
+	opt = middlewares.WithCost(opt, req.Cost)
+
+	key := descriptorsKey(req.Key, req.Descriptors)
+	decision, err := limiter.Decide(r.Context(), key, opt)
+	if err != nil {
+		logger.Error(fmt.Sprintf("error when executing the RateLimiter by %s", req.NameSpace), err)
+		writeError(w, http.StatusInternalServerError, "error when executing the RateLimiter")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(CheckResponse{
+		Allowed:      decision.Allowed,
+		NameSpace:    req.NameSpace,
+		Key:          key,
+		Limit:        decision.Limit,
+		Remaining:    max(0, decision.Remaining),
+		WindowMs:     decision.Window.Milliseconds(),
+		ResetAt:      decision.ResetAt,
+		ResetAfterMs: max(0, time.Until(decision.ResetAt).Milliseconds()),
+		RetryAfterMs: decision.RetryAfter.Milliseconds(),
+	})
+}
+
+// descriptorsKey appends the descriptors to the key sorted by name, such as
+// "acme-api:route=/login:tenant=acme".
+func descriptorsKey(key string, descriptors map[string]string) string {
+	names := make([]string, 0, len(descriptors))
+	for name := range descriptors {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var b strings.Builder
+	b.WriteString(key)
+	for _, name := range names {
+		fmt.Fprintf(&b, ":%s=%s", name, descriptors[name])
+	}
+	return b.String()
+}
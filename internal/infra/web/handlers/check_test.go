@@ -0,0 +1,97 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/GeovaneCavalcante/rate-limit-api/configs"
+	"github.com/GeovaneCavalcante/rate-limit-api/pkg/ratelimit"
+	mock_ratelimit "github.com/GeovaneCavalcante/rate-limit-api/pkg/ratelimit/mock"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/mock/gomock"
+)
+
+func TestCheckHandler(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	ipLimiter := mock_ratelimit.NewMockRateLimiterInterface(ctrl)
+	tokenLimiter := mock_ratelimit.NewMockRateLimiterInterface(ctrl)
+
+	h := NewCheckHandler(ipLimiter, tokenLimiter, []configs.TokenConfigLimit{
+		{Token: "123", MaxRequests: 10, WindowSecond: 60},
+	})
+
+	serve := func(method, body string) *httptest.ResponseRecorder {
+		req, err := http.NewRequest(method, "/v1/check", strings.NewReader(body))
+		assert.NoError(t, err)
+
+		rr := httptest.NewRecorder()
+		h.CheckHandler(rr, req)
+		return rr
+	}
+
+	t.Run("should return the decision of the ip limiter", func(t *testing.T) {
+		ipLimiter.EXPECT().Decide(gomock.Any(), "198.51.100.9", nil).Return(&ratelimit.Decision{
+			Allowed:   true,
+			Limit:     20,
+			Remaining: 19,
+			Window:    time.Minute,
+			ResetAt:   time.Now().Add(time.Minute),
+		}, nil)
+
+		rr := serve("POST", `{"namespace": "ip", "key": "198.51.100.9"}`)
+		assert.Equal(t, http.StatusOK, rr.Code)
+
+		var resp CheckResponse
+		assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &resp))
+		assert.True(t, resp.Allowed)
+		assert.Equal(t, int64(20), resp.Limit)
+		assert.Equal(t, int64(19), resp.Remaining)
+		assert.Equal(t, int64(60000), resp.WindowMs)
+		assert.InDelta(t, 60000, resp.ResetAfterMs, 1000)
+		assert.Equal(t, int64(0), resp.RetryAfterMs)
+	})
+
+	t.Run("should use the token policy with the cost and descriptors", func(t *testing.T) {
+		options := &ratelimit.Options{NameSpace: "token", MaxInInterval: 10, Interval: time.Minute, Cost: 3}
+		tokenLimiter.EXPECT().Decide(gomock.Any(), "123:route=/login:tenant=acme", options).Return(&ratelimit.Decision{
+			Allowed:    false,
+			Limit:      10,
+			RetryAfter: 1500 * time.Millisecond,
+		}, nil)
+
+		rr := serve("POST", `{"namespace": "token", "key": "123", "cost": 3, "descriptors": {"tenant": "acme", "route": "/login"}}`)
+		assert.Equal(t, http.StatusOK, rr.Code)
+
+		var resp CheckResponse
+		assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &resp))
+		assert.False(t, resp.Allowed)
+		assert.Equal(t, "123:route=/login:tenant=acme", resp.Key)
+		assert.Equal(t, int64(1500), resp.RetryAfterMs)
+	})
+
+	t.Run("should return not found for an unknown token", func(t *testing.T) {
+		rr := serve("POST", `{"namespace": "token", "key": "unknown"}`)
+		assert.Equal(t, http.StatusNotFound, rr.Code)
+	})
+
+	t.Run("should reject invalid requests", func(t *testing.T) {
+		assert.Equal(t, http.StatusMethodNotAllowed, serve("GET", "").Code)
+		assert.Equal(t, http.StatusBadRequest, serve("POST", `{`).Code)
+		assert.Equal(t, http.StatusBadRequest, serve("POST", `{"namespace": "tenant", "key": "acme"}`).Code)
+		assert.Equal(t, http.StatusBadRequest, serve("POST", `{"namespace": "ip"}`).Code)
+		assert.Equal(t, http.StatusBadRequest, serve("POST", `{"namespace": "ip", "key": "198.51.100.9", "cost": -1}`).Code)
+		assert.Equal(t, http.StatusBadRequest, serve("POST", `{"namespace": "ip", "key": "198.51.100.9", "cost": 10001}`).Code)
+		assert.Equal(t, http.StatusBadRequest, serve("POST", `{"namespace": "ip", "key": "198.51.100.9", "cost": 9223372036854775807}`).Code)
+	})
+
+	t.Run("should return an error when the limiter fails", func(t *testing.T) {
+		ipLimiter.EXPECT().Decide(gomock.Any(), "198.51.100.9", nil).Return(nil, assert.AnError)
+
+		rr := serve("POST", `{"namespace": "ip", "key": "198.51.100.9"}`)
+		assert.Equal(t, http.StatusInternalServerError, rr.Code)
+	})
+}
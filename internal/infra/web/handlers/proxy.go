@@ -0,0 +1,156 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/GeovaneCavalcante/rate-limit-api/configs"
+	"github.com/GeovaneCavalcante/rate-limit-api/internal/infra/web/middlewares"
+	"github.com/GeovaneCavalcante/rate-limit-api/pkg/logger"
+)
+
+// Upstream forwards the requests whose path starts with Prefix to Target.
+type Upstream struct {
+	Prefix        string
+	Target        *url.URL
+	StripPrefix   bool
+	PreserveHost  bool
+	SetHeaders    map[string]string
+	RemoveHeaders []string
+	proxy         *httputil.ReverseProxy
+}
+
+// ProxyHandler is the gateway in front of the upstream services, it is meant
+// to be wrapped by the rate limiter so only the allowed requests are sent.
+type ProxyHandler struct {
+	// Upstreams are sorted by the length of the prefix, the longest first.
+	Upstreams []*Upstream
+	// IPResolver keeps the X-Forwarded-For chain of the requests coming from
+	// its trusted proxies, the others only forward the address of the peer.
+	IPResolver *middlewares.ClientIPResolver
+}
+
+func NewProxyHandler(upstreams []configs.Upstream) (*ProxyHandler, error) {
+	h := &ProxyHandler{Upstreams: make([]*Upstream, 0, len(upstreams))}
+
+	for i, u := range upstreams {
+		if !strings.HasPrefix(u.Prefix, "/") {
+			return nil, fmt.Errorf("upstream %d must have a prefix starting with /", i)
+		}
+		target, err := url.Parse(u.URL)
+		if err != nil || target.Scheme == "" || target.Host == "" {
+			return nil, fmt.Errorf("upstream %d has an invalid url %q", i, u.URL)
+		}
+
+		upstream := &Upstream{
+			Prefix:        u.Prefix,
+			Target:        target,
+			StripPrefix:   u.StripPrefix,
+			PreserveHost:  u.PreserveHost,
+			SetHeaders:    u.SetHeaders,
+			RemoveHeaders: u.RemoveHeaders,
+		}
+		upstream.proxy = newReverseProxy(upstream, time.Duration(u.Timeout), h.trustsPeer)
+		h.Upstreams = append(h.Upstreams, upstream)
+	}
+
+	sort.SliceStable(h.Upstreams, func(i, j int) bool {
+		return len(h.Upstreams[i].Prefix) > len(h.Upstreams[j].Prefix)
+	})
+
+	return h, nil
+}
+
+func (h *ProxyHandler) ProxyHandler(w http.ResponseWriter, r *http.Request) {
+	upstream := h.match(r.URL.Path)
+	if upstream == nil {
+		writeError(w, http.StatusNotFound, "no upstream for the path")
+		return
+	}
+
+	upstream.proxy.ServeHTTP(w, r)
+}
+
+func (h *ProxyHandler) trustsPeer(r *http.Request) bool {
+	return h.IPResolver.TrustsPeer(r)
+}
+
+func (h *ProxyHandler) match(path string) *Upstream {
+	for _, u := range h.Upstreams {
+		if u.matches(path) {
+			return u
+		}
+	}
+	return nil
+}
+
+// matches compares whole segments, so "/api" matches "/api" and "/api/users"
+// but not "/apis".
+func (u *Upstream) matches(path string) bool {
+	prefix := strings.TrimSuffix(u.Prefix, "/")
+	return path == prefix || strings.HasPrefix(path, prefix+"/")
+}
+
+// rewrite keeps the inbound X-Forwarded-For only when the peer is a trusted
+// proxy, SetXForwarded then appends the peer so the upstream sees the whole
+// chain.
+func (u *Upstream) rewrite(pr *httputil.ProxyRequest, trustedPeer bool) {
+	if u.StripPrefix {
+		prefix := strings.TrimSuffix(u.Prefix, "/")
+		pr.Out.URL.Path = "/" + strings.TrimPrefix(strings.TrimPrefix(pr.Out.URL.Path, prefix), "/")
+		if pr.Out.URL.RawPath != "" {
+			pr.Out.URL.RawPath = "/" + strings.TrimPrefix(strings.TrimPrefix(pr.Out.URL.RawPath, prefix), "/")
+		}
+	}
+
+	pr.SetURL(u.Target)
+	if u.PreserveHost {
+		pr.Out.Host = pr.In.Host
+	}
+	if trustedPeer {
+		pr.Out.Header["X-Forwarded-For"] = pr.In.Header["X-Forwarded-For"]
+	}
+	pr.SetXForwarded()
+
+	for _, name := range u.RemoveHeaders {
+		pr.Out.Header.Del(name)
+	}
+	for name, value := range u.SetHeaders {
+		pr.Out.Header.Set(name, value)
+	}
+}
+
+func newReverseProxy(u *Upstream, timeout time.Duration, trustsPeer func(*http.Request) bool) *httputil.ReverseProxy {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.ResponseHeaderTimeout = timeout
+
+	return &httputil.ReverseProxy{
+		Rewrite: func(pr *httputil.ProxyRequest) {
+			u.rewrite(pr, trustsPeer(pr.In))
+		},
+		Transport: transport,
+		ErrorHandler: func(w http.ResponseWriter, r *http.Request, err error) {
+			logger.Error(fmt.Sprintf("error when forwarding the request to %s", u.Target), err)
+
+			var netErr net.Error
+			if errors.As(err, &netErr) && netErr.Timeout() {
+				writeError(w, http.StatusGatewayTimeout, "upstream timeout")
+				return
+			}
+			writeError(w, http.StatusBadGateway, "upstream unavailable")
+		},
+	}
+}
+
+func writeError(w http.ResponseWriter, status int, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	w.Write([]byte(fmt.Sprintf(`{"error": %q}`, message)))
+}
@@ -0,0 +1,161 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/GeovaneCavalcante/rate-limit-api/configs"
+	"github.com/GeovaneCavalcante/rate-limit-api/internal/infra/web/middlewares"
+	"github.com/stretchr/testify/assert"
+)
+
+type forwarded struct {
+	Upstream      string
+	Path          string
+	Host          string
+	XForwardedFor string
+	APIKey        string
+	Gateway       string
+}
+
+func newUpstreamServer(t *testing.T, name string) *httptest.Server {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(forwarded{
+			Upstream:      name,
+			Path:          r.URL.Path,
+			Host:          r.Host,
+			XForwardedFor: r.Header.Get("X-Forwarded-For"),
+			APIKey:        r.Header.Get("API_KEY"),
+			Gateway:       r.Header.Get("X-Gateway"),
+		})
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestProxyHandler(t *testing.T) {
+	users := newUpstreamServer(t, "users")
+	api := newUpstreamServer(t, "api")
+
+	h, err := NewProxyHandler([]configs.Upstream{
+		{Prefix: "/api", URL: api.URL},
+		{
+			Prefix:        "/api/users/",
+			URL:           users.URL + "/v1",
+			StripPrefix:   true,
+			PreserveHost:  true,
+			SetHeaders:    map[string]string{"X-Gateway": "rate-limit-api"},
+			RemoveHeaders: []string{"API_KEY"},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	serve := func(path string) (*httptest.ResponseRecorder, forwarded) {
+		req, err := http.NewRequest("GET", path, nil)
+		assert.NoError(t, err)
+		req.Host = "gateway.example.com"
+		req.RemoteAddr = "198.51.100.9:1234"
+		req.Header.Set("API_KEY", "123")
+
+		rr := httptest.NewRecorder()
+		h.ProxyHandler(rr, req)
+
+		var f forwarded
+		json.Unmarshal(rr.Body.Bytes(), &f)
+		return rr, f
+	}
+
+	t.Run("should forward to the upstream with the longest prefix", func(t *testing.T) {
+		rr, f := serve("/api/users/42")
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.Equal(t, "users", f.Upstream)
+		assert.Equal(t, "/v1/42", f.Path)
+		assert.Equal(t, "gateway.example.com", f.Host)
+		assert.Equal(t, "198.51.100.9", f.XForwardedFor)
+		assert.Equal(t, "rate-limit-api", f.Gateway)
+		assert.Empty(t, f.APIKey)
+	})
+
+	t.Run("should keep the path and headers without rewriting", func(t *testing.T) {
+		rr, f := serve("/api/orders")
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.Equal(t, "api", f.Upstream)
+		assert.Equal(t, "/api/orders", f.Path)
+		assert.Equal(t, api.Listener.Addr().String(), f.Host)
+		assert.Equal(t, "123", f.APIKey)
+	})
+
+	t.Run("should keep the forwarding chain of a trusted proxy", func(t *testing.T) {
+		resolver, err := middlewares.NewClientIPResolver([]string{"10.0.0.0/8"}, "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		h.IPResolver = resolver
+		defer func() { h.IPResolver = nil }()
+
+		forward := func(remoteAddr string) forwarded {
+			req := httptest.NewRequest("GET", "/api/orders", nil)
+			req.RemoteAddr = remoteAddr
+			req.Header.Set("X-Forwarded-For", "198.51.100.9, 10.0.0.2")
+
+			rr := httptest.NewRecorder()
+			h.ProxyHandler(rr, req)
+			assert.Equal(t, http.StatusOK, rr.Code)
+
+			var f forwarded
+			json.Unmarshal(rr.Body.Bytes(), &f)
+			return f
+		}
+
+		assert.Equal(t, "198.51.100.9, 10.0.0.2, 10.0.0.1", forward("10.0.0.1:1234").XForwardedFor)
+		assert.Equal(t, "203.0.113.7", forward("203.0.113.7:1234").XForwardedFor)
+	})
+
+	t.Run("should match whole path segments", func(t *testing.T) {
+		rr, _ := serve("/apis")
+		assert.Equal(t, http.StatusNotFound, rr.Code)
+	})
+
+	t.Run("should return bad gateway when the upstream is unavailable", func(t *testing.T) {
+		down := httptest.NewServer(http.NotFoundHandler())
+		down.Close()
+
+		h, err := NewProxyHandler([]configs.Upstream{{Prefix: "/", URL: down.URL}})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		rr := httptest.NewRecorder()
+		h.ProxyHandler(rr, httptest.NewRequest("GET", "/", nil))
+		assert.Equal(t, http.StatusBadGateway, rr.Code)
+	})
+
+	t.Run("should return gateway timeout when the upstream is slow", func(t *testing.T) {
+		slow := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			time.Sleep(200 * time.Millisecond)
+		}))
+		defer slow.Close()
+
+		h, err := NewProxyHandler([]configs.Upstream{{Prefix: "/", URL: slow.URL, Timeout: configs.Duration(20 * time.Millisecond)}})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		rr := httptest.NewRecorder()
+		h.ProxyHandler(rr, httptest.NewRequest("GET", "/", nil))
+		assert.Equal(t, http.StatusGatewayTimeout, rr.Code)
+	})
+
+	t.Run("should return an error when an upstream is invalid", func(t *testing.T) {
+		_, err := NewProxyHandler([]configs.Upstream{{Prefix: "api", URL: api.URL}})
+		assert.ErrorContains(t, err, "must have a prefix starting with /")
+
+		_, err = NewProxyHandler([]configs.Upstream{{Prefix: "/api", URL: "localhost:8080"}})
+		assert.ErrorContains(t, err, "invalid url")
+	})
+}
@@ -0,0 +1,67 @@
+package middlewares
+
+import (
+	"net/http"
+	"net/url"
+)
+
+// AuthHandler answers the subrequests of nginx auth_request and Traefik
+// ForwardAuth: the original method and URI are read from the X-Original-*
+// or X-Forwarded-* headers and the request goes through the same rules and
+// limits as RateLimiter, answering 200 when it is allowed. The client
+// address comes from X-Forwarded-For, so the proxy must be one of the
+// trusted proxies of the IPResolver. The rejected requests are answered with
+// AuthDeniedStatus when it is set.
+func (l *Limiter) AuthHandler() http.HandlerFunc {
+	limited := l.RateLimiter(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		if l.AuthDeniedStatus != 0 {
+			w = deniedStatusWriter{ResponseWriter: w, status: l.AuthDeniedStatus}
+		}
+		limited.ServeHTTP(w, originalRequest(r))
+	}
+}
+
+// deniedStatusWriter writes status in place of 429.
+type deniedStatusWriter struct {
+	http.ResponseWriter
+	status int
+}
+
+func (w deniedStatusWriter) WriteHeader(code int) {
+	if code == http.StatusTooManyRequests {
+		code = w.status
+	}
+	w.ResponseWriter.WriteHeader(code)
+}
+
+// originalRequest rebuilds the request the proxy is authorizing.
+func originalRequest(r *http.Request) *http.Request {
+	method := firstHeader(r.Header, "X-Original-Method", "X-Forwarded-Method")
+	uri := firstHeader(r.Header, "X-Original-URI", "X-Forwarded-Uri")
+	if method == "" && uri == "" {
+		return r
+	}
+
+	original := r.Clone(r.Context())
+	if method != "" {
+		original.Method = method
+	}
+	if u, err := url.ParseRequestURI(uri); err == nil {
+		original.URL = u
+		original.RequestURI = uri
+	}
+	return original
+}
+
+func firstHeader(h http.Header, names ...string) string {
+	for _, name := range names {
+		if value := h.Get(name); value != "" {
+			return value
+		}
+	}
+	return ""
+}
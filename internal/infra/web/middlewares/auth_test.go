@@ -0,0 +1,103 @@
+package middlewares
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"time"
+
+	"github.com/GeovaneCavalcante/rate-limit-api/configs"
+	"github.com/GeovaneCavalcante/rate-limit-api/pkg/ratelimit"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/mock/gomock"
+)
+
+func (suite *RateLimiterTestSuite) TestAuthHandler() {
+	policies, err := NewRoutePolicies([]configs.RoutePolicy{
+		{Method: "POST", Path: "/login", IPConfigLimit: configs.IPConfigLimit{MaxRequests: 5, WindowSecond: 60}},
+	})
+	if err != nil {
+		suite.FailNow(err.Error())
+	}
+	resolver, err := NewClientIPResolver([]string{"10.0.0.0/8"}, "")
+	if err != nil {
+		suite.FailNow(err.Error())
+	}
+
+	var deniedStatus int
+	serve := func(headers map[string]string) *httptest.ResponseRecorder {
+		req, err := http.NewRequest("GET", "/v1/auth", nil)
+		assert.NoError(suite.T(), err)
+		req.RemoteAddr = "10.0.0.1:1234"
+		for name, value := range headers {
+			req.Header.Set(name, value)
+		}
+
+		m := NewLimiter(suite.RateLimitToken, suite.RateLimitIp, suite.TokensConfig)
+		m.RouteLimiter = suite.RateLimitRoute
+		m.RoutePolicies = policies
+		m.IPResolver = resolver
+		m.AuthDeniedStatus = deniedStatus
+
+		rr := httptest.NewRecorder()
+		m.AuthHandler().ServeHTTP(rr, req)
+		return rr
+	}
+
+	suite.Run("should limit the original request of nginx", func() {
+		options := &ratelimit.Options{MaxInInterval: 5, Interval: time.Minute}
+		suite.RateLimitRoute.EXPECT().Decide(gomock.Any(), "POST /login:ip:198.51.100.9", options).Return(&ratelimit.Decision{Allowed: false, Limit: 5, RetryAfter: 10 * time.Second}, nil)
+
+		rr := serve(map[string]string{
+			"X-Original-Method": "POST",
+			"X-Original-URI":    "/login?next=/home",
+			"X-Forwarded-For":   "198.51.100.9",
+		})
+		assert.Equal(suite.T(), http.StatusTooManyRequests, rr.Code)
+		assert.Equal(suite.T(), "10", rr.Header().Get("Retry-After"))
+	})
+
+	suite.Run("should read the forwarded headers of traefik", func() {
+		options := &ratelimit.Options{MaxInInterval: 5, Interval: time.Minute}
+		suite.RateLimitRoute.EXPECT().Decide(gomock.Any(), "POST /login:token:123", options).Return(&ratelimit.Decision{Allowed: true, Limit: 5, Remaining: 4}, nil)
+
+		rr := serve(map[string]string{
+			"X-Forwarded-Method": "POST",
+			"X-Forwarded-Uri":    "/login",
+			"X-Forwarded-For":    "198.51.100.9",
+			"API_KEY":            "123",
+		})
+		assert.Equal(suite.T(), http.StatusOK, rr.Code)
+		assert.Equal(suite.T(), "4", rr.Header().Get("RateLimit-Remaining"))
+	})
+
+	suite.Run("should limit the client ip on the other routes", func() {
+		suite.RateLimitIp.EXPECT().Decide(gomock.Any(), "198.51.100.9", nil).Return(&ratelimit.Decision{Allowed: true}, nil)
+
+		rr := serve(map[string]string{
+			"X-Original-Method": "GET",
+			"X-Original-URI":    "/login",
+			"X-Forwarded-For":   "198.51.100.9",
+		})
+		assert.Equal(suite.T(), http.StatusOK, rr.Code)
+	})
+
+	suite.Run("should answer the configured status when limited", func() {
+		deniedStatus = http.StatusForbidden
+		defer func() { deniedStatus = 0 }()
+
+		suite.RateLimitIp.EXPECT().Decide(gomock.Any(), "198.51.100.9", nil).Return(&ratelimit.Decision{Allowed: false, Limit: 20, RetryAfter: 10 * time.Second}, nil)
+		rr := serve(map[string]string{"X-Original-URI": "/", "X-Forwarded-For": "198.51.100.9"})
+		assert.Equal(suite.T(), http.StatusForbidden, rr.Code)
+		assert.Equal(suite.T(), "10", rr.Header().Get("Retry-After"))
+		assert.Equal(suite.T(), "20", rr.Header().Get("RateLimit-Limit"))
+
+		suite.RateLimitIp.EXPECT().Decide(gomock.Any(), "198.51.100.9", nil).Return(nil, assert.AnError)
+		rr = serve(map[string]string{"X-Original-URI": "/", "X-Forwarded-For": "198.51.100.9"})
+		assert.Equal(suite.T(), http.StatusInternalServerError, rr.Code)
+	})
+
+	suite.Run("should reject an unknown token", func() {
+		rr := serve(map[string]string{"X-Original-URI": "/", "API_KEY": "unknown"})
+		assert.Equal(suite.T(), http.StatusUnauthorized, rr.Code)
+	})
+}
@@ -0,0 +1,187 @@
+package middlewares
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+)
+
+// The forwarding headers a ClientIPResolver can read the client address from.
+const (
+	ClientIPHeaderXForwardedFor = "x-forwarded-for"
+	ClientIPHeaderForwarded     = "forwarded"
+	ClientIPHeaderXRealIP       = "x-real-ip"
+)
+
+// ClientIPResolver finds the address of the client that sent a request. The
+// Header is only honoured when the connection comes from one of the
+// TrustedProxies, otherwise anyone could pick the key of their own bucket.
+// Only the Header set by the proxies is read, the other forwarding headers
+// are passed through unchanged by most proxies and can be forged.
+type ClientIPResolver struct {
+	TrustedProxies []netip.Prefix
+	// Header is one of the ClientIPHeader constants.
+	Header string
+}
+
+// NewClientIPResolver parses the trusted proxies, given as CIDRs or single
+// addresses. The header defaults to ClientIPHeaderXForwardedFor when empty.
+func NewClientIPResolver(trustedProxies []string, header string) (*ClientIPResolver, error) {
+	prefixes, err := parsePrefixes(trustedProxies)
+	if err != nil {
+		return nil, fmt.Errorf("invalid trusted proxy: %w", err)
+	}
+
+	header = strings.ToLower(header)
+	switch header {
+	case "":
+		header = ClientIPHeaderXForwardedFor
+	case ClientIPHeaderXForwardedFor, ClientIPHeaderForwarded, ClientIPHeaderXRealIP:
+	default:
+		return nil, fmt.Errorf("unknown client ip header %q", header)
+	}
+
+	return &ClientIPResolver{TrustedProxies: prefixes, Header: header}, nil
+}
+
+// parsePrefixes parses CIDRs, a single address is taken as its own network.
+func parsePrefixes(cidrs []string) ([]netip.Prefix, error) {
+	prefixes := make([]netip.Prefix, 0, len(cidrs))
+
+	for _, cidr := range cidrs {
+		cidr = strings.TrimSpace(cidr)
+		if cidr == "" {
+			continue
+		}
+
+		if !strings.Contains(cidr, "/") {
+			addr, err := netip.ParseAddr(cidr)
+			if err != nil {
+				return nil, fmt.Errorf("%q: %w", cidr, err)
+			}
+			addr = addr.Unmap()
+			prefixes = append(prefixes, netip.PrefixFrom(addr, addr.BitLen()))
+			continue
+		}
+
+		prefix, err := netip.ParsePrefix(cidr)
+		if err != nil {
+			return nil, fmt.Errorf("%q: %w", cidr, err)
+		}
+		if prefix.Addr().Is4In6() {
+			prefix = netip.PrefixFrom(prefix.Addr().Unmap(), prefix.Bits()-96)
+		}
+		prefixes = append(prefixes, prefix.Masked())
+	}
+
+	return prefixes, nil
+}
+
+// ClientIP returns the client address without port. When the peer is a
+// trusted proxy the forwarding chain of the Header is walked from right to
+// left and the first address that is not a trusted proxy is the client.
+func (c *ClientIPResolver) ClientIP(r *http.Request) string {
+	remote, ok := parseHost(r.RemoteAddr)
+	if !ok {
+		return r.RemoteAddr
+	}
+	if !c.trusted(remote) {
+		return remote.String()
+	}
+
+	var chain []string
+	switch c.Header {
+	case ClientIPHeaderForwarded:
+		chain = forwardedFor(r.Header)
+	case ClientIPHeaderXRealIP:
+		if realIP, ok := parseHost(r.Header.Get("X-Real-IP")); ok {
+			return realIP.String()
+		}
+	default:
+		chain = xForwardedFor(r.Header)
+	}
+	if len(chain) == 0 {
+		return remote.String()
+	}
+
+	client := remote
+	for i := len(chain) - 1; i >= 0; i-- {
+		addr, ok := parseHost(chain[i])
+		if !ok {
+			break
+		}
+		client = addr
+		if !c.trusted(addr) {
+			break
+		}
+	}
+
+	return client.String()
+}
+
+// TrustsPeer reports whether the connection of r comes from a trusted proxy,
+// whose forwarding headers can be kept.
+func (c *ClientIPResolver) TrustsPeer(r *http.Request) bool {
+	remote, ok := parseHost(r.RemoteAddr)
+	return ok && c.trusted(remote)
+}
+
+func (c *ClientIPResolver) trusted(addr netip.Addr) bool {
+	if c == nil {
+		return false
+	}
+	for _, prefix := range c.TrustedProxies {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+func xForwardedFor(h http.Header) []string {
+	var chain []string
+	for _, value := range h.Values("X-Forwarded-For") {
+		for _, hop := range strings.Split(value, ",") {
+			chain = append(chain, strings.TrimSpace(hop))
+		}
+	}
+	return chain
+}
+
+// forwardedFor returns the for= parameters of the RFC 7239 Forwarded header.
+func forwardedFor(h http.Header) []string {
+	var chain []string
+	for _, value := range h.Values("Forwarded") {
+		for _, element := range strings.Split(value, ",") {
+			for _, pair := range strings.Split(element, ";") {
+				name, value, found := strings.Cut(strings.TrimSpace(pair), "=")
+				if found && strings.EqualFold(name, "for") {
+					chain = append(chain, strings.Trim(value, `"`))
+				}
+			}
+		}
+	}
+	return chain
+}
+
+// parseHost parses an address that may carry a port or IPv6 brackets, such
+// as "192.0.2.1:4711" or "[2001:db8::1]:4711".
+func parseHost(s string) (netip.Addr, bool) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return netip.Addr{}, false
+	}
+
+	if host, _, err := net.SplitHostPort(s); err == nil {
+		s = host
+	}
+	s = strings.TrimSuffix(strings.TrimPrefix(s, "["), "]")
+
+	addr, err := netip.ParseAddr(s)
+	if err != nil {
+		return netip.Addr{}, false
+	}
+	return addr.Unmap().WithZone(""), true
+}
@@ -0,0 +1,149 @@
+package middlewares
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestClientIP(t *testing.T) {
+	resolvers := map[string]*ClientIPResolver{}
+	for _, header := range []string{ClientIPHeaderXForwardedFor, ClientIPHeaderForwarded, ClientIPHeaderXRealIP} {
+		resolver, err := NewClientIPResolver([]string{"10.0.0.0/8", "2001:db8:ffff::1"}, header)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resolvers[header] = resolver
+	}
+
+	tests := []struct {
+		name       string
+		header     string
+		remoteAddr string
+		headers    map[string]string
+		expected   string
+	}{
+		{
+			name:       "should strip the port of the connection address",
+			remoteAddr: "203.0.113.7:51234",
+			expected:   "203.0.113.7",
+		},
+		{
+			name:       "should strip the port of an ipv6 connection address",
+			remoteAddr: "[2001:db8::7]:51234",
+			expected:   "2001:db8::7",
+		},
+		{
+			name:       "should ignore the forwarding headers of an untrusted peer",
+			remoteAddr: "203.0.113.7:51234",
+			headers:    map[string]string{"X-Forwarded-For": "1.2.3.4", "X-Real-IP": "1.2.3.4"},
+			expected:   "203.0.113.7",
+		},
+		{
+			name:       "should walk x-forwarded-for from right to left",
+			remoteAddr: "10.0.0.1:443",
+			headers:    map[string]string{"X-Forwarded-For": "1.2.3.4, 198.51.100.9, 10.0.0.2"},
+			expected:   "198.51.100.9",
+		},
+		{
+			name:       "should return the left most hop when every proxy is trusted",
+			remoteAddr: "10.0.0.1:443",
+			headers:    map[string]string{"X-Forwarded-For": "198.51.100.9, 10.0.0.3, 10.0.0.2"},
+			expected:   "198.51.100.9",
+		},
+		{
+			name:       "should stop at an invalid hop",
+			remoteAddr: "10.0.0.1:443",
+			headers:    map[string]string{"X-Forwarded-For": "198.51.100.9, garbage"},
+			expected:   "10.0.0.1",
+		},
+		{
+			name:       "should ignore a forged forwarded header when the proxy uses x-forwarded-for",
+			remoteAddr: "10.0.0.1:443",
+			headers: map[string]string{
+				"Forwarded":       "for=192.0.2.60",
+				"X-Real-IP":       "192.0.2.61",
+				"X-Forwarded-For": "198.51.100.9",
+			},
+			expected: "198.51.100.9",
+		},
+		{
+			name:       "should not fall back to another header",
+			remoteAddr: "10.0.0.1:443",
+			headers:    map[string]string{"Forwarded": "for=192.0.2.60", "X-Real-IP": "192.0.2.61"},
+			expected:   "10.0.0.1",
+		},
+		{
+			name:       "should read the forwarded header when configured",
+			header:     ClientIPHeaderForwarded,
+			remoteAddr: "10.0.0.1:443",
+			headers: map[string]string{
+				"Forwarded":       `for=192.0.2.60;proto=http, for="[2001:db8:cafe::17]:4711";by=10.0.0.1`,
+				"X-Forwarded-For": "1.2.3.4",
+			},
+			expected: "2001:db8:cafe::17",
+		},
+		{
+			name:       "should skip the trusted proxies of the forwarded header",
+			header:     ClientIPHeaderForwarded,
+			remoteAddr: "[2001:db8:ffff::1]:443",
+			headers:    map[string]string{"Forwarded": `for=192.0.2.60, for="10.1.2.3:80"`},
+			expected:   "192.0.2.60",
+		},
+		{
+			name:       "should read x-real-ip when configured",
+			header:     ClientIPHeaderXRealIP,
+			remoteAddr: "10.0.0.1:443",
+			headers:    map[string]string{"X-Real-IP": "198.51.100.9", "X-Forwarded-For": "1.2.3.4"},
+			expected:   "198.51.100.9",
+		},
+		{
+			name:       "should use the proxy address when it sends no header",
+			remoteAddr: "10.0.0.1:443",
+			expected:   "10.0.0.1",
+		},
+		{
+			name:       "should unmap ipv4 addresses",
+			remoteAddr: "[::ffff:10.0.0.1]:443",
+			headers:    map[string]string{"X-Forwarded-For": "::ffff:198.51.100.9"},
+			expected:   "198.51.100.9",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, err := http.NewRequest("GET", "/", nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			req.RemoteAddr = tt.remoteAddr
+			for name, value := range tt.headers {
+				req.Header.Set(name, value)
+			}
+
+			header := tt.header
+			if header == "" {
+				header = ClientIPHeaderXForwardedFor
+			}
+			assert.Equal(t, tt.expected, resolvers[header].ClientIP(req))
+		})
+	}
+
+	t.Run("should ignore the forwarding headers without resolver", func(t *testing.T) {
+		var resolver *ClientIPResolver
+		req, _ := http.NewRequest("GET", "/", nil)
+		req.RemoteAddr = "10.0.0.1:443"
+		req.Header.Set("X-Forwarded-For", "1.2.3.4")
+
+		assert.Equal(t, "10.0.0.1", resolver.ClientIP(req))
+	})
+
+	t.Run("should return an error when a trusted proxy is invalid", func(t *testing.T) {
+		_, err := NewClientIPResolver([]string{"10.0.0.0/33"}, "")
+		assert.Error(t, err)
+
+		_, err = NewClientIPResolver([]string{"10.0.0.0/8"}, "x-client-ip")
+		assert.ErrorContains(t, err, "unknown client ip header")
+	})
+}
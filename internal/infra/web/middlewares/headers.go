@@ -0,0 +1,44 @@
+package middlewares
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/GeovaneCavalcante/rate-limit-api/pkg/ratelimit"
+)
+
+// setRateLimitHeaders writes the RateLimit headers of the IETF httpapi
+// ratelimit-headers draft and, on rejected requests, Retry-After.
+func (l *Limiter) setRateLimitHeaders(w http.ResponseWriter, d *ratelimit.Decision) {
+	h := w.Header()
+	SetRateLimitHeaders(h, d)
+
+	if l.LegacyHeaders {
+		h.Set("X-RateLimit-Limit", strconv.FormatInt(d.Limit, 10))
+		h.Set("X-RateLimit-Remaining", strconv.FormatInt(max(0, d.Remaining), 10))
+		h.Set("X-RateLimit-Reset", strconv.FormatInt(d.ResetAt.Unix(), 10))
+	}
+}
+
+// SetRateLimitHeaders sets the RateLimit headers of the decision, and
+// Retry-After when it is a rejection, without the legacy ones.
+func SetRateLimitHeaders(h http.Header, d *ratelimit.Decision) {
+	h.Set("RateLimit-Limit", strconv.FormatInt(d.Limit, 10))
+	h.Set("RateLimit-Remaining", strconv.FormatInt(max(0, d.Remaining), 10))
+	h.Set("RateLimit-Reset", strconv.FormatInt(ceilSeconds(time.Until(d.ResetAt)), 10))
+	h.Set("RateLimit-Policy", fmt.Sprintf("%d;w=%d", d.Limit, ceilSeconds(d.Window)))
+
+	if !d.Allowed {
+		h.Set("Retry-After", strconv.FormatInt(ceilSeconds(d.RetryAfter), 10))
+	}
+}
+
+func ceilSeconds(d time.Duration) int64 {
+	if d <= 0 {
+		return 0
+	}
+	return int64(math.Ceil(d.Seconds()))
+}
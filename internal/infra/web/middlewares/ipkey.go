@@ -0,0 +1,34 @@
+package middlewares
+
+import "net/netip"
+
+// IPPrefixes groups the client addresses in networks before they are used
+// as limiter keys, so a client rotating addresses inside the range it
+// controls still hits the same bucket. Zero keeps the full address.
+type IPPrefixes struct {
+	IPv4 int
+	IPv6 int
+}
+
+// Key returns the network of the address for the configured prefix, such as
+// "2001:db8:1:2::/64", or the address itself when it is not grouped.
+func (p IPPrefixes) Key(ip string) string {
+	addr, err := netip.ParseAddr(ip)
+	if err != nil {
+		return ip
+	}
+
+	bits := p.IPv6
+	if addr.Is4() {
+		bits = p.IPv4
+	}
+	if bits <= 0 || bits >= addr.BitLen() {
+		return addr.String()
+	}
+
+	prefix, err := addr.Prefix(bits)
+	if err != nil {
+		return addr.String()
+	}
+	return prefix.String()
+}
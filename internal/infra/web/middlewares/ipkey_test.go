@@ -0,0 +1,30 @@
+package middlewares
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIPPrefixesKey(t *testing.T) {
+	tests := []struct {
+		name     string
+		prefixes IPPrefixes
+		ip       string
+		expected string
+	}{
+		{name: "should keep the address without prefixes", ip: "2001:db8:1:2:3:4:5:6", expected: "2001:db8:1:2:3:4:5:6"},
+		{name: "should group ipv6 addresses in a /64", prefixes: IPPrefixes{IPv6: 64}, ip: "2001:db8:1:2:3:4:5:6", expected: "2001:db8:1:2::/64"},
+		{name: "should group ipv6 addresses in a /56", prefixes: IPPrefixes{IPv6: 56}, ip: "2001:db8:1:2ff:3:4:5:6", expected: "2001:db8:1:200::/56"},
+		{name: "should keep ipv4 addresses when only ipv6 is grouped", prefixes: IPPrefixes{IPv6: 64}, ip: "198.51.100.9", expected: "198.51.100.9"},
+		{name: "should group ipv4 addresses in a /24", prefixes: IPPrefixes{IPv4: 24, IPv6: 64}, ip: "198.51.100.9", expected: "198.51.100.0/24"},
+		{name: "should keep the address when the prefix is the full length", prefixes: IPPrefixes{IPv4: 32}, ip: "198.51.100.9", expected: "198.51.100.9"},
+		{name: "should keep values that are not addresses", prefixes: IPPrefixes{IPv4: 24}, ip: "unknown", expected: "unknown"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, tt.prefixes.Key(tt.ip))
+		})
+	}
+}
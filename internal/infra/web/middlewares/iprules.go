@@ -0,0 +1,82 @@
+package middlewares
+
+import (
+	"fmt"
+	"net/netip"
+
+	"github.com/GeovaneCavalcante/rate-limit-api/configs"
+	"github.com/GeovaneCavalcante/rate-limit-api/pkg/ratelimit"
+)
+
+const (
+	IPRuleAllow = "allow"
+	IPRuleDeny  = "deny"
+	IPRuleLimit = "limit"
+)
+
+// IPRule is an action applied to the clients of some networks. Options is
+// only set for the IPRuleLimit action.
+type IPRule struct {
+	Prefixes []netip.Prefix
+	Action   string
+	Options  *ratelimit.Options
+}
+
+// IPRules are evaluated in order and the first rule matching the client
+// address wins.
+type IPRules []IPRule
+
+func NewIPRules(rules []configs.IPRule) (IPRules, error) {
+	ipRules := make(IPRules, 0, len(rules))
+
+	for i, rule := range rules {
+		prefixes, err := parsePrefixes(rule.CIDRs)
+		if err != nil {
+			return nil, fmt.Errorf("invalid cidr in ip rule %d: %w", i, err)
+		}
+		if len(prefixes) == 0 {
+			return nil, fmt.Errorf("ip rule %d has no cidrs", i)
+		}
+
+		ipRule := IPRule{Prefixes: prefixes, Action: rule.Action}
+
+		switch rule.Action {
+		case IPRuleAllow, IPRuleDeny:
+		case IPRuleLimit:
+			if len(rule.Limits) == 0 && (rule.MaxRequests <= 0 || rule.Interval() <= 0) {
+				return nil, fmt.Errorf("ip rule %d must set max_requests and window, or limits", i)
+			}
+			ipRule.Options = &ratelimit.Options{
+				MaxInInterval: rule.MaxRequests,
+				Interval:      rule.Interval(),
+				Burst:         rule.Burst,
+				BlockDuration: rule.Block(),
+				Limits:        ToLimits(rule.Limits),
+			}
+		default:
+			return nil, fmt.Errorf("unknown action %q in ip rule %d", rule.Action, i)
+		}
+
+		ipRules = append(ipRules, ipRule)
+	}
+
+	return ipRules, nil
+}
+
+// Match returns the first rule containing the address, or nil.
+func (rs IPRules) Match(ip string) *IPRule {
+	addr, err := netip.ParseAddr(ip)
+	if err != nil {
+		return nil
+	}
+	addr = addr.Unmap()
+
+	for i := range rs {
+		for _, prefix := range rs[i].Prefixes {
+			if prefix.Contains(addr) {
+				return &rs[i]
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,54 @@
+package middlewares
+
+import (
+	"testing"
+	"time"
+
+	"github.com/GeovaneCavalcante/rate-limit-api/configs"
+	"github.com/GeovaneCavalcante/rate-limit-api/pkg/ratelimit"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIPRules(t *testing.T) {
+	rules, err := NewIPRules([]configs.IPRule{
+		{CIDRs: []string{"10.0.0.1"}, Action: IPRuleDeny},
+		{CIDRs: []string{"10.0.0.0/8", "2001:db8::/32"}, Action: IPRuleAllow},
+		{CIDRs: []string{"203.0.113.0/24"}, Action: IPRuleLimit, IPConfigLimit: configs.IPConfigLimit{MaxRequests: 1000, WindowSecond: 60}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("should return the first matching rule", func(t *testing.T) {
+		assert.Equal(t, IPRuleDeny, rules.Match("10.0.0.1").Action)
+		assert.Equal(t, IPRuleAllow, rules.Match("10.0.0.2").Action)
+		assert.Equal(t, IPRuleAllow, rules.Match("2001:db8::1").Action)
+		assert.Equal(t, IPRuleAllow, rules.Match("::ffff:10.1.2.3").Action)
+	})
+
+	t.Run("should return the options of the limit rule", func(t *testing.T) {
+		rule := rules.Match("203.0.113.9")
+		assert.Equal(t, IPRuleLimit, rule.Action)
+		assert.Equal(t, &ratelimit.Options{MaxInInterval: 1000, Interval: time.Minute}, rule.Options)
+	})
+
+	t.Run("should return nil when no rule matches", func(t *testing.T) {
+		assert.Nil(t, rules.Match("198.51.100.9"))
+		assert.Nil(t, rules.Match("unknown"))
+		assert.Nil(t, IPRules(nil).Match("10.0.0.1"))
+	})
+
+	t.Run("should return an error when a rule is invalid", func(t *testing.T) {
+		invalid := map[string]configs.IPRule{
+			"invalid cidr in ip rule 0": {CIDRs: []string{"10.0.0.0/33"}, Action: IPRuleDeny},
+			"ip rule 0 has no cidrs":    {Action: IPRuleDeny},
+			"unknown action \"block\"":  {CIDRs: []string{"10.0.0.0/8"}, Action: "block"},
+			"must set max_requests":     {CIDRs: []string{"10.0.0.0/8"}, Action: IPRuleLimit},
+		}
+
+		for message, rule := range invalid {
+			_, err := NewIPRules([]configs.IPRule{rule})
+			assert.ErrorContains(t, err, message)
+		}
+	})
+}
@@ -3,16 +3,58 @@ package middlewares
 import (
 	"fmt"
 	"net/http"
+	"strconv"
+	"strings"
 
 	"github.com/GeovaneCavalcante/rate-limit-api/configs"
 	"github.com/GeovaneCavalcante/rate-limit-api/pkg/logger"
 	"github.com/GeovaneCavalcante/rate-limit-api/pkg/ratelimit"
 )
 
+const (
+	// ModeFirst enforces only the first limit that applies: the route
+	// policy, then the token, then the token and ip and then the ip.
+	ModeFirst = "first"
+	// ModeAll enforces every limit that applies, in the ModeFirst order, and
+	// rejects the request when any of them is exceeded. The limits after the
+	// first rejection are not called, like the windows of a MultiLimiter, so
+	// a rejected request does not consume them.
+	ModeAll = "all"
+)
+
 type Limiter struct {
-	TokenLimiter      ratelimit.RateLimiterInterface
-	IPLimiter         ratelimit.RateLimiterInterface
+	TokenLimiter ratelimit.RateLimiterInterface
+	IPLimiter    ratelimit.RateLimiterInterface
+	// RouteLimiter enforces the RoutePolicies in place of the token and ip
+	// limits on the routes they match.
+	RouteLimiter  ratelimit.RateLimiterInterface
+	RoutePolicies RoutePolicies
+	// TokenIPLimiter limits each token per client network, keyed by the
+	// token and the TokenIPPrefixes network, so a leaked token can not be
+	// used from many addresses. It is disabled when nil.
+	TokenIPLimiter  ratelimit.RateLimiterInterface
+	TokenIPPrefixes IPPrefixes
+	// Mode is ModeFirst, the default, or ModeAll.
+	Mode              string
 	TokensConfigLimit []configs.TokenConfigLimit
+	// LegacyHeaders also emits the X-RateLimit-* headers.
+	LegacyHeaders bool
+	// IPResolver extracts the client address, when nil the forwarding
+	// headers are ignored and the address of the connection is used.
+	IPResolver *ClientIPResolver
+	// IPPrefixes groups the client addresses in networks for the ip limiter.
+	IPPrefixes IPPrefixes
+	// IPRules allow, deny or set their own limit to some networks before
+	// the limiters are called.
+	IPRules IPRules
+	// CostHeader names the request header, set by a trusted component in
+	// front of the API, that carries the cost of the request. It takes
+	// precedence over the cost of the route policy.
+	CostHeader string
+	// AuthDeniedStatus replaces the 429 of the requests rejected by
+	// AuthHandler, such as 403 for nginx auth_request that treats any other
+	// status as an error.
+	AuthDeniedStatus int
 }
 
 func NewLimiter(tokenLimiter, ipLimiter ratelimit.RateLimiterInterface, tk []configs.TokenConfigLimit) Limiter {
@@ -27,69 +69,162 @@ func (l *Limiter) RateLimiter(next http.Handler) http.HandlerFunc {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 
 		token := r.Header.Get("API_KEY")
-		ip := getIP(r)
+		ip := l.IPResolver.ClientIP(r)
+
+		var ipOptions *ratelimit.Options
+		ipKind := "ip"
+		if rule := l.IPRules.Match(ip); rule != nil {
+			switch rule.Action {
+			case IPRuleDeny:
+				logger.Warn(fmt.Sprintf("IPRULE - ip %s is denied", ip), nil)
+				writeResponse(w, http.StatusForbidden, `forbidden`)
+				return
+			case IPRuleAllow:
+				next.ServeHTTP(w, r)
+				return
+			}
+			ipOptions = rule.Options
+			ipKind = "ip_rule"
+		}
 
+		var tokenOptions *ratelimit.Options
 		if token != "" {
-
-			tokenOptions := findOpionsByToken(l.TokensConfigLimit, token)
+			tokenOptions = TokenOptions(l.TokensConfigLimit, token)
 			if tokenOptions == nil {
 				logger.Error(fmt.Sprintf("token %s not found", token), nil)
-				w.WriteHeader(http.StatusUnauthorized)
-				w.Header().Set("Content-Type", "application/json")
-				w.Write([]byte(`token not found`))
-				return
-			}
-			tokenLimiter, err := l.TokenLimiter.Limiter(r.Context(), token, tokenOptions)
-			if err != nil {
-				logger.Error("error when executing the RateLimiter by token", err)
-				w.WriteHeader(http.StatusInternalServerError)
-				w.Header().Set("Content-Type", "application/json")
-				w.Write([]byte(`error when executing the RateLimiter`))
+				writeResponse(w, http.StatusUnauthorized, `token not found`)
 				return
 			}
+		}
 
-			if tokenLimiter {
-				logger.Warn("TOKENLIMIT - you have reached the maximum number of requests or actions allowed within a certain time frame", nil)
-				w.WriteHeader(http.StatusTooManyRequests)
-				w.Header().Set("Content-Type", "application/json")
-				w.Write([]byte(`you have reached the maximum number of requests or actions allowed within a certain time frame`))
-				return
+		var checks []check
+		var cost int64
+		if policy := l.RoutePolicies.Match(r); policy != nil {
+			cost = policy.Cost
+			if policy.Options != nil {
+				client := "ip:" + l.IPPrefixes.Key(ip)
+				if token != "" {
+					client = "token:" + token
+				}
+				checks = append(checks, check{"route", l.RouteLimiter, policy.Key(client), policy.Options})
 			}
-			if !tokenLimiter {
-				next.ServeHTTP(w, r)
-				return
+		}
+		if token != "" {
+			checks = append(checks, check{"token", l.TokenLimiter, token, tokenOptions})
+			if l.TokenIPLimiter != nil {
+				checks = append(checks, check{"token_ip", l.TokenIPLimiter, token + ":" + l.TokenIPPrefixes.Key(ip), nil})
 			}
 		}
+		checks = append(checks, check{ipKind, l.IPLimiter, l.IPPrefixes.Key(ip), ipOptions})
 
-		ipLimiter, err := l.IPLimiter.Limiter(r.Context(), ip, nil)
-
-		if err != nil {
-			logger.Error("error when executing the RateLimiter by ip", err)
-			w.WriteHeader(http.StatusInternalServerError)
-			w.Header().Set("Content-Type", "application/json")
-			w.Write([]byte(`error when executing the RateLimiter`))
-			return
+		if headerCost, ok := l.requestCost(r); ok {
+			cost = headerCost
+		}
+		for i := range checks {
+			checks[i].opt = WithCost(checks[i].opt, cost)
 		}
 
-		if ipLimiter {
-			logger.Warn("IPLIMIT - you have reached the maximum number of requests or actions allowed within a certain time frame", nil)
-			w.WriteHeader(http.StatusTooManyRequests)
-			w.Header().Set("Content-Type", "application/json")
-			w.Write([]byte(`you have reached the maximum number of requests or actions allowed within a certain time frame`))
-			return
+		if l.Mode != ModeAll {
+			checks = checks[:1]
 		}
 
-		next.ServeHTTP(w, r)
+		if l.allow(w, r, checks) {
+			next.ServeHTTP(w, r)
+		}
 	})
 }
 
-func findOpionsByToken(tk []configs.TokenConfigLimit, token string) *ratelimit.Options {
+// requestCost reads the cost of the request from the CostHeader.
+func (l *Limiter) requestCost(r *http.Request) (int64, bool) {
+	if l.CostHeader == "" {
+		return 0, false
+	}
+	value := r.Header.Get(l.CostHeader)
+	if value == "" {
+		return 0, false
+	}
+
+	cost, err := strconv.ParseInt(value, 10, 64)
+	if err != nil || cost <= 0 || cost > ratelimit.MaxCost {
+		logger.Warn(fmt.Sprintf("invalid request cost %q in %s", value, l.CostHeader), err)
+		return 0, false
+	}
+	return cost, true
+}
+
+// WithCost returns a copy of the options with the cost, the options are
+// shared between requests and must not be changed in place.
+func WithCost(opt *ratelimit.Options, cost int64) *ratelimit.Options {
+	if cost <= 1 {
+		return opt
+	}
+
+	weighted := ratelimit.Options{}
+	if opt != nil {
+		weighted = *opt
+	}
+	weighted.Cost = cost
+	return &weighted
+}
+
+// check is one limit that applies to the request, kind is reported as the
+// Rule of its decision.
+type check struct {
+	kind    string
+	limiter ratelimit.RateLimiterInterface
+	key     string
+	opt     *ratelimit.Options
+}
+
+// allow runs the checks until one of them rejects the request and sets the
+// RateLimit headers of the most restrictive decision. When the request is
+// rejected or a limiter fails the response is written and false returned.
+func (l *Limiter) allow(w http.ResponseWriter, r *http.Request, checks []check) bool {
+	decisions := make([]*ratelimit.Decision, 0, len(checks))
+
+	for _, c := range checks {
+		decision, err := c.limiter.Decide(r.Context(), c.key, c.opt)
+		if err != nil {
+			logger.Error(fmt.Sprintf("error when executing the RateLimiter by %s", c.kind), err)
+			writeResponse(w, http.StatusInternalServerError, `error when executing the RateLimiter`)
+			return false
+		}
+		decision.Rule = c.kind
+		decisions = append(decisions, decision)
+		if !decision.Allowed {
+			break
+		}
+	}
+
+	decision := ratelimit.MostRestrictive(decisions...)
+	l.setRateLimitHeaders(w, decision)
+
+	if !decision.Allowed {
+		logger.Warn(fmt.Sprintf("%sLIMIT - you have reached the maximum number of requests or actions allowed within a certain time frame", strings.ToUpper(decision.Rule)), nil)
+		writeResponse(w, http.StatusTooManyRequests, `you have reached the maximum number of requests or actions allowed within a certain time frame`)
+		return false
+	}
+
+	return true
+}
+
+func writeResponse(w http.ResponseWriter, status int, body string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	w.Write([]byte(body))
+}
+
+// TokenOptions returns the limit of the token, or nil when it is not configured.
+func TokenOptions(tk []configs.TokenConfigLimit, token string) *ratelimit.Options {
 	for _, t := range tk {
 		if t.Token == token {
 			return &ratelimit.Options{
-				NameSpace:      "token",
-				MaxInInterval:  t.MaxRequests,
-				IntervalSecund: t.BlockTimeSecond,
+				NameSpace:     "token",
+				MaxInInterval: t.MaxRequests,
+				Interval:      t.Interval(),
+				Burst:         t.Burst,
+				BlockDuration: t.Block(),
+				Limits:        ToLimits(t.Limits),
 			}
 		}
 	}
@@ -97,11 +232,19 @@ func findOpionsByToken(tk []configs.TokenConfigLimit, token string) *ratelimit.O
 	return nil
 }
 
-func getIP(r *http.Request) string {
-	forwarded := r.Header.Get("X-Forwarded-For")
-	if forwarded != "" {
-		return forwarded
+// ToLimits converts the configured windows to the ratelimit.MultiLimiter ones.
+func ToLimits(limits []configs.Limit) []ratelimit.Limit {
+	if len(limits) == 0 {
+		return nil
 	}
 
-	return r.RemoteAddr
+	result := make([]ratelimit.Limit, 0, len(limits))
+	for _, l := range limits {
+		result = append(result, ratelimit.Limit{
+			MaxInInterval: l.MaxRequests,
+			Interval:      l.Interval(),
+			Burst:         l.Burst,
+		})
+	}
+	return result
 }
@@ -3,9 +3,12 @@ package middlewares
 import (
 	"net/http"
 	"net/http/httptest"
+	"strconv"
 	"testing"
+	"time"
 
 	"github.com/GeovaneCavalcante/rate-limit-api/configs"
+	"github.com/GeovaneCavalcante/rate-limit-api/pkg/ratelimit"
 	mock_ratelimit "github.com/GeovaneCavalcante/rate-limit-api/pkg/ratelimit/mock"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/suite"
@@ -14,15 +17,19 @@ import (
 
 type RateLimiterTestSuite struct {
 	suite.Suite
-	RateLimitToken *mock_ratelimit.MockRateLimiterInterface
-	RateLimitIp    *mock_ratelimit.MockRateLimiterInterface
-	TokensConfig   []configs.TokenConfigLimit
+	RateLimitToken   *mock_ratelimit.MockRateLimiterInterface
+	RateLimitIp      *mock_ratelimit.MockRateLimiterInterface
+	RateLimitRoute   *mock_ratelimit.MockRateLimiterInterface
+	RateLimitTokenIP *mock_ratelimit.MockRateLimiterInterface
+	TokensConfig     []configs.TokenConfigLimit
 }
 
 func (suite *RateLimiterTestSuite) SetupTest() {
 	ctrl := gomock.NewController(suite.T())
 	suite.RateLimitToken = mock_ratelimit.NewMockRateLimiterInterface(ctrl)
 	suite.RateLimitIp = mock_ratelimit.NewMockRateLimiterInterface(ctrl)
+	suite.RateLimitRoute = mock_ratelimit.NewMockRateLimiterInterface(ctrl)
+	suite.RateLimitTokenIP = mock_ratelimit.NewMockRateLimiterInterface(ctrl)
 	suite.TokensConfig = []configs.TokenConfigLimit{
 		{
 			Token:           "123",
@@ -34,7 +41,7 @@ func (suite *RateLimiterTestSuite) SetupTest() {
 
 func (suite *RateLimiterTestSuite) TestRateLimiter() {
 	suite.Run("should return request successfully", func() {
-		suite.RateLimitToken.EXPECT().Limiter(gomock.Any(), gomock.Any(), gomock.Any()).Return(false, nil)
+		suite.RateLimitToken.EXPECT().Decide(gomock.Any(), gomock.Any(), gomock.Any()).Return(&ratelimit.Decision{Allowed: true}, nil)
 		testHandler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
 		req, err := http.NewRequest("GET", "/", nil)
 		req.Header.Set("API_KEY", "123")
@@ -69,7 +76,7 @@ func (suite *RateLimiterTestSuite) TestRateLimiter() {
 	})
 
 	suite.Run("should return an error when the limiter by token returns an error", func() {
-		suite.RateLimitToken.EXPECT().Limiter(gomock.Any(), gomock.Any(), gomock.Any()).Return(false, assert.AnError)
+		suite.RateLimitToken.EXPECT().Decide(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil, assert.AnError)
 		testHandler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
 		req, err := http.NewRequest("GET", "/", nil)
 		req.Header.Set("API_KEY", "123")
@@ -87,7 +94,7 @@ func (suite *RateLimiterTestSuite) TestRateLimiter() {
 	})
 
 	suite.Run("should return an error when the limiter by ip returns an error", func() {
-		suite.RateLimitIp.EXPECT().Limiter(gomock.Any(), gomock.Any(), gomock.Any()).Return(false, assert.AnError)
+		suite.RateLimitIp.EXPECT().Decide(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil, assert.AnError)
 		testHandler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
 		req, err := http.NewRequest("GET", "/", nil)
 
@@ -104,7 +111,7 @@ func (suite *RateLimiterTestSuite) TestRateLimiter() {
 	})
 
 	suite.Run("should return an error when the limiter by token returns true", func() {
-		suite.RateLimitToken.EXPECT().Limiter(gomock.Any(), gomock.Any(), gomock.Any()).Return(true, nil)
+		suite.RateLimitToken.EXPECT().Decide(gomock.Any(), gomock.Any(), gomock.Any()).Return(&ratelimit.Decision{Allowed: false}, nil)
 		testHandler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
 		req, err := http.NewRequest("GET", "/", nil)
 		req.Header.Set("API_KEY", "123")
@@ -122,7 +129,7 @@ func (suite *RateLimiterTestSuite) TestRateLimiter() {
 	})
 
 	suite.Run("should return an error when the limiter by ip returns true", func() {
-		suite.RateLimitToken.EXPECT().Limiter(gomock.Any(), gomock.Any(), gomock.Any()).Return(true, nil)
+		suite.RateLimitToken.EXPECT().Decide(gomock.Any(), gomock.Any(), gomock.Any()).Return(&ratelimit.Decision{Allowed: false}, nil)
 		testHandler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
 		req, err := http.NewRequest("GET", "/", nil)
 		req.Header.Set("API_KEY", "123")
@@ -142,6 +149,403 @@ func (suite *RateLimiterTestSuite) TestRateLimiter() {
 
 }
 
+func (suite *RateLimiterTestSuite) TestIPPrefixes() {
+	suite.Run("should limit the ipv6 clients by network", func() {
+		suite.RateLimitIp.EXPECT().Decide(gomock.Any(), "2001:db8:1:2::/64", nil).Return(&ratelimit.Decision{Allowed: true}, nil).Times(2)
+		testHandler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+
+		m := NewLimiter(suite.RateLimitToken, suite.RateLimitIp, suite.TokensConfig)
+		m.IPPrefixes = IPPrefixes{IPv6: 64}
+		handler := m.RateLimiter(testHandler)
+
+		for _, addr := range []string{"[2001:db8:1:2::1]:1234", "[2001:db8:1:2:ffff::9]:1234"} {
+			req, err := http.NewRequest("GET", "/", nil)
+			assert.NoError(suite.T(), err)
+			req.RemoteAddr = addr
+
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, req)
+			assert.Equal(suite.T(), http.StatusOK, rr.Code)
+		}
+	})
+}
+
+func (suite *RateLimiterTestSuite) TestIPRules() {
+	rules, err := NewIPRules([]configs.IPRule{
+		{CIDRs: []string{"192.0.2.0/24"}, Action: IPRuleDeny},
+		{CIDRs: []string{"10.0.0.0/8"}, Action: IPRuleAllow},
+		{CIDRs: []string{"203.0.113.0/24"}, Action: IPRuleLimit, IPConfigLimit: configs.IPConfigLimit{MaxRequests: 1000, WindowSecond: 60}},
+	})
+	if err != nil {
+		suite.FailNow(err.Error())
+	}
+
+	serve := func(remoteAddr, token string) *httptest.ResponseRecorder {
+		testHandler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+		req, err := http.NewRequest("GET", "/", nil)
+		assert.NoError(suite.T(), err)
+		req.RemoteAddr = remoteAddr
+		if token != "" {
+			req.Header.Set("API_KEY", token)
+		}
+
+		m := NewLimiter(suite.RateLimitToken, suite.RateLimitIp, suite.TokensConfig)
+		m.IPRules = rules
+
+		rr := httptest.NewRecorder()
+		m.RateLimiter(testHandler).ServeHTTP(rr, req)
+		return rr
+	}
+
+	suite.Run("should deny the clients of a denied network", func() {
+		rr := serve("192.0.2.7:1234", "123")
+		assert.Equal(suite.T(), http.StatusForbidden, rr.Code)
+		assert.Equal(suite.T(), "forbidden", rr.Body.String())
+	})
+
+	suite.Run("should not limit the clients of an allowed network", func() {
+		rr := serve("10.1.2.3:1234", "")
+		assert.Equal(suite.T(), http.StatusOK, rr.Code)
+		assert.Empty(suite.T(), rr.Header().Get("RateLimit-Limit"))
+	})
+
+	suite.Run("should apply the limit of the rule", func() {
+		decision := &ratelimit.Decision{Allowed: true}
+		suite.RateLimitIp.EXPECT().Decide(gomock.Any(), "203.0.113.9", &ratelimit.Options{MaxInInterval: 1000, Interval: time.Minute}).Return(decision, nil)
+
+		rr := serve("203.0.113.9:1234", "")
+		assert.Equal(suite.T(), http.StatusOK, rr.Code)
+		assert.Equal(suite.T(), "ip_rule", decision.Rule)
+	})
+
+	suite.Run("should apply the default limit when no rule matches", func() {
+		suite.RateLimitIp.EXPECT().Decide(gomock.Any(), "198.51.100.9", nil).Return(&ratelimit.Decision{Allowed: true}, nil)
+
+		rr := serve("198.51.100.9:1234", "")
+		assert.Equal(suite.T(), http.StatusOK, rr.Code)
+	})
+}
+
+func (suite *RateLimiterTestSuite) TestRoutePolicies() {
+	policies, err := NewRoutePolicies([]configs.RoutePolicy{
+		{Method: "POST", Path: "/login", IPConfigLimit: configs.IPConfigLimit{MaxRequests: 5, WindowSecond: 60}},
+	})
+	if err != nil {
+		suite.FailNow(err.Error())
+	}
+
+	serve := func(method, token string) *httptest.ResponseRecorder {
+		testHandler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+		req, err := http.NewRequest(method, "/login", nil)
+		assert.NoError(suite.T(), err)
+		req.RemoteAddr = "198.51.100.9:1234"
+		if token != "" {
+			req.Header.Set("API_KEY", token)
+		}
+
+		m := NewLimiter(suite.RateLimitToken, suite.RateLimitIp, suite.TokensConfig)
+		m.RouteLimiter = suite.RateLimitRoute
+		m.RoutePolicies = policies
+
+		rr := httptest.NewRecorder()
+		m.RateLimiter(testHandler).ServeHTTP(rr, req)
+		return rr
+	}
+
+	options := &ratelimit.Options{MaxInInterval: 5, Interval: time.Minute}
+
+	suite.Run("should limit the ip on the matched route", func() {
+		suite.RateLimitRoute.EXPECT().Decide(gomock.Any(), "POST /login:ip:198.51.100.9", options).Return(&ratelimit.Decision{Allowed: false}, nil)
+
+		rr := serve("POST", "")
+		assert.Equal(suite.T(), http.StatusTooManyRequests, rr.Code)
+	})
+
+	suite.Run("should limit the token on the matched route", func() {
+		suite.RateLimitRoute.EXPECT().Decide(gomock.Any(), "POST /login:token:123", options).Return(&ratelimit.Decision{Allowed: true}, nil)
+
+		rr := serve("POST", "123")
+		assert.Equal(suite.T(), http.StatusOK, rr.Code)
+	})
+
+	suite.Run("should reject an unknown token before the route limit", func() {
+		rr := serve("POST", "unknown")
+		assert.Equal(suite.T(), http.StatusUnauthorized, rr.Code)
+	})
+
+	suite.Run("should use the ip limit on the other routes", func() {
+		suite.RateLimitIp.EXPECT().Decide(gomock.Any(), "198.51.100.9", nil).Return(&ratelimit.Decision{Allowed: true}, nil)
+
+		rr := serve("GET", "")
+		assert.Equal(suite.T(), http.StatusOK, rr.Code)
+	})
+}
+
+func (suite *RateLimiterTestSuite) TestModeAll() {
+	serve := func() *httptest.ResponseRecorder {
+		testHandler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+		req, err := http.NewRequest("GET", "/", nil)
+		assert.NoError(suite.T(), err)
+		req.RemoteAddr = "198.51.100.9:1234"
+		req.Header.Set("API_KEY", "123")
+
+		m := NewLimiter(suite.RateLimitToken, suite.RateLimitIp, suite.TokensConfig)
+		m.Mode = ModeAll
+
+		rr := httptest.NewRecorder()
+		m.RateLimiter(testHandler).ServeHTTP(rr, req)
+		return rr
+	}
+
+	suite.Run("should enforce the ip limit of a token holder", func() {
+		suite.RateLimitToken.EXPECT().Decide(gomock.Any(), "123", gomock.Any()).Return(&ratelimit.Decision{Allowed: true, Limit: 100, Remaining: 99}, nil)
+		suite.RateLimitIp.EXPECT().Decide(gomock.Any(), "198.51.100.9", nil).Return(&ratelimit.Decision{Allowed: false, Limit: 20, RetryAfter: 30 * time.Second}, nil)
+
+		rr := serve()
+		assert.Equal(suite.T(), http.StatusTooManyRequests, rr.Code)
+		assert.Equal(suite.T(), "20", rr.Header().Get("RateLimit-Limit"))
+		assert.Equal(suite.T(), "30", rr.Header().Get("Retry-After"))
+	})
+
+	suite.Run("should not consume the limits after the first rejection", func() {
+		suite.RateLimitToken.EXPECT().Decide(gomock.Any(), "123", gomock.Any()).Return(&ratelimit.Decision{Allowed: false, Limit: 100, RetryAfter: 10 * time.Second}, nil)
+
+		rr := serve()
+		assert.Equal(suite.T(), http.StatusTooManyRequests, rr.Code)
+		assert.Equal(suite.T(), "100", rr.Header().Get("RateLimit-Limit"))
+		assert.Equal(suite.T(), "10", rr.Header().Get("Retry-After"))
+	})
+
+	suite.Run("should report the limit with fewer remaining requests", func() {
+		suite.RateLimitToken.EXPECT().Decide(gomock.Any(), "123", gomock.Any()).Return(&ratelimit.Decision{Allowed: true, Limit: 100, Remaining: 3}, nil)
+		suite.RateLimitIp.EXPECT().Decide(gomock.Any(), "198.51.100.9", nil).Return(&ratelimit.Decision{Allowed: true, Limit: 20, Remaining: 19}, nil)
+
+		rr := serve()
+		assert.Equal(suite.T(), http.StatusOK, rr.Code)
+		assert.Equal(suite.T(), "100", rr.Header().Get("RateLimit-Limit"))
+		assert.Equal(suite.T(), "3", rr.Header().Get("RateLimit-Remaining"))
+	})
+
+	suite.Run("should return an error when any limiter fails", func() {
+		suite.RateLimitToken.EXPECT().Decide(gomock.Any(), "123", gomock.Any()).Return(&ratelimit.Decision{Allowed: true}, nil)
+		suite.RateLimitIp.EXPECT().Decide(gomock.Any(), "198.51.100.9", nil).Return(nil, assert.AnError)
+
+		rr := serve()
+		assert.Equal(suite.T(), http.StatusInternalServerError, rr.Code)
+	})
+}
+
+func (suite *RateLimiterTestSuite) TestTokenIP() {
+	serve := func(remoteAddr, token string) *httptest.ResponseRecorder {
+		testHandler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+		req, err := http.NewRequest("GET", "/", nil)
+		assert.NoError(suite.T(), err)
+		req.RemoteAddr = remoteAddr
+		if token != "" {
+			req.Header.Set("API_KEY", token)
+		}
+
+		m := NewLimiter(suite.RateLimitToken, suite.RateLimitIp, suite.TokensConfig)
+		m.Mode = ModeAll
+		m.TokenIPLimiter = suite.RateLimitTokenIP
+		m.TokenIPPrefixes = IPPrefixes{IPv6: 64}
+
+		rr := httptest.NewRecorder()
+		m.RateLimiter(testHandler).ServeHTTP(rr, req)
+		return rr
+	}
+
+	suite.Run("should limit the token per client network", func() {
+		suite.RateLimitToken.EXPECT().Decide(gomock.Any(), "123", gomock.Any()).Return(&ratelimit.Decision{Allowed: true, Limit: 100, Remaining: 99}, nil)
+		suite.RateLimitTokenIP.EXPECT().Decide(gomock.Any(), "123:2001:db8:1:2::/64", nil).Return(&ratelimit.Decision{Allowed: false, Limit: 10, RetryAfter: 5 * time.Second}, nil)
+
+		rr := serve("[2001:db8:1:2::9]:1234", "123")
+		assert.Equal(suite.T(), http.StatusTooManyRequests, rr.Code)
+		assert.Equal(suite.T(), "10", rr.Header().Get("RateLimit-Limit"))
+	})
+
+	suite.Run("should report the rule of the decision", func() {
+		token := &ratelimit.Decision{Allowed: true, Limit: 100, Remaining: 99}
+		tokenIP := &ratelimit.Decision{Allowed: true, Limit: 10, Remaining: 2}
+		ip := &ratelimit.Decision{Allowed: true, Limit: 20, Remaining: 19}
+		suite.RateLimitToken.EXPECT().Decide(gomock.Any(), "123", gomock.Any()).Return(token, nil)
+		suite.RateLimitTokenIP.EXPECT().Decide(gomock.Any(), "123:198.51.100.9", nil).Return(tokenIP, nil)
+		suite.RateLimitIp.EXPECT().Decide(gomock.Any(), "198.51.100.9", nil).Return(ip, nil)
+
+		rr := serve("198.51.100.9:1234", "123")
+		assert.Equal(suite.T(), http.StatusOK, rr.Code)
+		assert.Equal(suite.T(), "token", token.Rule)
+		assert.Equal(suite.T(), "token_ip", tokenIP.Rule)
+		assert.Equal(suite.T(), "ip", ip.Rule)
+	})
+
+	suite.Run("should not apply the token and ip limit without a token", func() {
+		suite.RateLimitIp.EXPECT().Decide(gomock.Any(), "198.51.100.9", nil).Return(&ratelimit.Decision{Allowed: true}, nil)
+
+		rr := serve("198.51.100.9:1234", "")
+		assert.Equal(suite.T(), http.StatusOK, rr.Code)
+	})
+}
+
+func (suite *RateLimiterTestSuite) TestRequestCost() {
+	policies, err := NewRoutePolicies([]configs.RoutePolicy{
+		{Path: "/export", Cost: 10},
+		{Path: "/search", Cost: 5, IPConfigLimit: configs.IPConfigLimit{MaxRequests: 50, WindowSecond: 60}},
+	})
+	if err != nil {
+		suite.FailNow(err.Error())
+	}
+
+	serve := func(path, cost string) *httptest.ResponseRecorder {
+		testHandler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+		req, err := http.NewRequest("GET", path, nil)
+		assert.NoError(suite.T(), err)
+		req.RemoteAddr = "198.51.100.9:1234"
+		if cost != "" {
+			req.Header.Set("X-Request-Cost", cost)
+		}
+
+		m := NewLimiter(suite.RateLimitToken, suite.RateLimitIp, suite.TokensConfig)
+		m.RouteLimiter = suite.RateLimitRoute
+		m.RoutePolicies = policies
+		m.CostHeader = "X-Request-Cost"
+
+		rr := httptest.NewRecorder()
+		m.RateLimiter(testHandler).ServeHTTP(rr, req)
+		return rr
+	}
+
+	suite.Run("should charge the cost of the route to the ip limit", func() {
+		suite.RateLimitIp.EXPECT().Decide(gomock.Any(), "198.51.100.9", &ratelimit.Options{Cost: 10}).Return(&ratelimit.Decision{Allowed: true}, nil)
+
+		rr := serve("/export", "")
+		assert.Equal(suite.T(), http.StatusOK, rr.Code)
+	})
+
+	suite.Run("should charge the cost of the route to its own limit", func() {
+		options := &ratelimit.Options{MaxInInterval: 50, Interval: time.Minute, Cost: 5}
+		suite.RateLimitRoute.EXPECT().Decide(gomock.Any(), "* /search:ip:198.51.100.9", options).Return(&ratelimit.Decision{Allowed: true}, nil)
+
+		rr := serve("/search", "")
+		assert.Equal(suite.T(), http.StatusOK, rr.Code)
+		assert.Equal(suite.T(), int64(0), policies[1].Options.Cost)
+	})
+
+	suite.Run("should prefer the cost of the header", func() {
+		suite.RateLimitIp.EXPECT().Decide(gomock.Any(), "198.51.100.9", &ratelimit.Options{Cost: 3}).Return(&ratelimit.Decision{Allowed: true}, nil)
+
+		rr := serve("/export", "3")
+		assert.Equal(suite.T(), http.StatusOK, rr.Code)
+	})
+
+	suite.Run("should ignore an invalid cost header", func() {
+		for _, cost := range []string{"-1", "abc", "10001", "9223372036854775807", "99999999999999999999"} {
+			suite.RateLimitIp.EXPECT().Decide(gomock.Any(), "198.51.100.9", &ratelimit.Options{Cost: 10}).Return(&ratelimit.Decision{Allowed: true}, nil)
+
+			rr := serve("/export", cost)
+			assert.Equal(suite.T(), http.StatusOK, rr.Code, cost)
+		}
+	})
+
+	suite.Run("should accept the maximum cost header", func() {
+		suite.RateLimitIp.EXPECT().Decide(gomock.Any(), "198.51.100.9", &ratelimit.Options{Cost: ratelimit.MaxCost}).Return(&ratelimit.Decision{Allowed: true}, nil)
+
+		rr := serve("/export", "10000")
+		assert.Equal(suite.T(), http.StatusOK, rr.Code)
+	})
+
+	suite.Run("should charge one unit by default", func() {
+		suite.RateLimitIp.EXPECT().Decide(gomock.Any(), "198.51.100.9", nil).Return(&ratelimit.Decision{Allowed: true}, nil)
+
+		rr := serve("/", "")
+		assert.Equal(suite.T(), http.StatusOK, rr.Code)
+	})
+}
+
+func (suite *RateLimiterTestSuite) TestRateLimitHeaders() {
+	suite.Run("should return the rate limit headers when the request is allowed", func() {
+		suite.RateLimitIp.EXPECT().Decide(gomock.Any(), gomock.Any(), gomock.Any()).Return(&ratelimit.Decision{
+			Allowed:   true,
+			Limit:     20,
+			Window:    60 * time.Second,
+			Remaining: 7,
+			ResetAt:   time.Now().Add(30 * time.Second),
+		}, nil)
+		testHandler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+		req, err := http.NewRequest("GET", "/", nil)
+
+		rr := httptest.NewRecorder()
+
+		m := NewLimiter(suite.RateLimitToken, suite.RateLimitIp, suite.TokensConfig)
+
+		handler := m.RateLimiter(testHandler)
+
+		handler.ServeHTTP(rr, req)
+		assert.NoError(suite.T(), err)
+		assert.Equal(suite.T(), http.StatusOK, rr.Code)
+		assert.Equal(suite.T(), "20", rr.Header().Get("RateLimit-Limit"))
+		assert.Equal(suite.T(), "7", rr.Header().Get("RateLimit-Remaining"))
+		assert.Equal(suite.T(), "30", rr.Header().Get("RateLimit-Reset"))
+		assert.Equal(suite.T(), "20;w=60", rr.Header().Get("RateLimit-Policy"))
+		assert.Empty(suite.T(), rr.Header().Get("Retry-After"))
+		assert.Empty(suite.T(), rr.Header().Get("X-RateLimit-Limit"))
+	})
+
+	suite.Run("should return the retry after when the request is rejected", func() {
+		suite.RateLimitToken.EXPECT().Decide(gomock.Any(), gomock.Any(), gomock.Any()).Return(&ratelimit.Decision{
+			Allowed:    false,
+			Limit:      1,
+			Window:     time.Second,
+			Remaining:  0,
+			ResetAt:    time.Now().Add(1500 * time.Millisecond),
+			RetryAfter: 1500 * time.Millisecond,
+		}, nil)
+		testHandler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+		req, err := http.NewRequest("GET", "/", nil)
+		req.Header.Set("API_KEY", "123")
+
+		rr := httptest.NewRecorder()
+
+		m := NewLimiter(suite.RateLimitToken, suite.RateLimitIp, suite.TokensConfig)
+
+		handler := m.RateLimiter(testHandler)
+
+		handler.ServeHTTP(rr, req)
+		assert.NoError(suite.T(), err)
+		assert.Equal(suite.T(), http.StatusTooManyRequests, rr.Code)
+		assert.Equal(suite.T(), "application/json", rr.Header().Get("Content-Type"))
+		assert.Equal(suite.T(), "0", rr.Header().Get("RateLimit-Remaining"))
+		assert.Equal(suite.T(), "2", rr.Header().Get("RateLimit-Reset"))
+		assert.Equal(suite.T(), "2", rr.Header().Get("Retry-After"))
+	})
+
+	suite.Run("should return the legacy headers when enabled", func() {
+		resetAt := time.Now().Add(30 * time.Second)
+		suite.RateLimitIp.EXPECT().Decide(gomock.Any(), gomock.Any(), gomock.Any()).Return(&ratelimit.Decision{
+			Allowed:   true,
+			Limit:     20,
+			Window:    60 * time.Second,
+			Remaining: 7,
+			ResetAt:   resetAt,
+		}, nil)
+		testHandler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+		req, err := http.NewRequest("GET", "/", nil)
+
+		rr := httptest.NewRecorder()
+
+		m := NewLimiter(suite.RateLimitToken, suite.RateLimitIp, suite.TokensConfig)
+		m.LegacyHeaders = true
+
+		handler := m.RateLimiter(testHandler)
+
+		handler.ServeHTTP(rr, req)
+		assert.NoError(suite.T(), err)
+		assert.Equal(suite.T(), "20", rr.Header().Get("X-RateLimit-Limit"))
+		assert.Equal(suite.T(), "7", rr.Header().Get("X-RateLimit-Remaining"))
+		assert.Equal(suite.T(), strconv.FormatInt(resetAt.Unix(), 10), rr.Header().Get("X-RateLimit-Reset"))
+	})
+}
+
 func TestSuite(t *testing.T) {
 	suite.Run(t, new(RateLimiterTestSuite))
 }
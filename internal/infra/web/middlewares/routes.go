@@ -0,0 +1,115 @@
+package middlewares
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/GeovaneCavalcante/rate-limit-api/configs"
+	"github.com/GeovaneCavalcante/rate-limit-api/pkg/ratelimit"
+)
+
+// RoutePolicy is the limit of the requests matching a method and a path
+// pattern. Its buckets are keyed by the route, so each route is limited
+// separately from the others.
+type RoutePolicy struct {
+	Method  string
+	Pattern string
+	// Options is nil when the policy only sets the Cost of the route.
+	Options *ratelimit.Options
+	// Cost is the number of units each request of the route consumes.
+	Cost     int64
+	segments []string
+	subtree  bool
+}
+
+// RoutePolicies are evaluated in order and the first matching policy wins.
+type RoutePolicies []RoutePolicy
+
+func NewRoutePolicies(policies []configs.RoutePolicy) (RoutePolicies, error) {
+	routePolicies := make(RoutePolicies, 0, len(policies))
+
+	for i, policy := range policies {
+		if !strings.HasPrefix(policy.Path, "/") {
+			return nil, fmt.Errorf("route policy %d must have a path starting with /", i)
+		}
+		routePolicy := RoutePolicy{
+			Method:   strings.ToUpper(policy.Method),
+			Pattern:  policy.Path,
+			Cost:     policy.Cost,
+			segments: splitPath(policy.Path),
+			subtree:  strings.HasSuffix(policy.Path, "/"),
+		}
+
+		hasLimit := len(policy.Limits) > 0 || policy.MaxRequests > 0
+		switch {
+		case hasLimit && len(policy.Limits) == 0 && policy.Interval() <= 0:
+			return nil, fmt.Errorf("route policy %d must set max_requests and window, or limits", i)
+		case !hasLimit && policy.Cost <= 0:
+			return nil, fmt.Errorf("route policy %d must set max_requests and window, limits or cost", i)
+		case policy.Cost < 0 || policy.Cost > ratelimit.MaxCost:
+			return nil, fmt.Errorf("route policy %d must have a cost between 0 and %d", i, ratelimit.MaxCost)
+		case hasLimit:
+			routePolicy.Options = &ratelimit.Options{
+				MaxInInterval: policy.MaxRequests,
+				Interval:      policy.Interval(),
+				Burst:         policy.Burst,
+				BlockDuration: policy.Block(),
+				Limits:        ToLimits(policy.Limits),
+			}
+		}
+
+		routePolicies = append(routePolicies, routePolicy)
+	}
+
+	return routePolicies, nil
+}
+
+// Match returns the first policy matching the request, or nil.
+func (rp RoutePolicies) Match(r *http.Request) *RoutePolicy {
+	for i := range rp {
+		if rp[i].matches(r.Method, r.URL.Path) {
+			return &rp[i]
+		}
+	}
+	return nil
+}
+
+// Key is the bucket of the client on this route, such as
+// "POST /login:ip:198.51.100.9".
+func (p *RoutePolicy) Key(client string) string {
+	method := p.Method
+	if method == "" {
+		method = "*"
+	}
+	return fmt.Sprintf("%s %s:%s", method, p.Pattern, client)
+}
+
+func (p *RoutePolicy) matches(method, path string) bool {
+	if p.Method != "" && p.Method != method {
+		return false
+	}
+
+	segments := splitPath(path)
+	if len(segments) < len(p.segments) || !p.subtree && len(segments) != len(p.segments) {
+		return false
+	}
+
+	for i, segment := range p.segments {
+		if segment == "*" || strings.HasPrefix(segment, "{") && strings.HasSuffix(segment, "}") {
+			continue
+		}
+		if segment != segments[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func splitPath(path string) []string {
+	path = strings.Trim(path, "/")
+	if path == "" {
+		return nil
+	}
+	return strings.Split(path, "/")
+}
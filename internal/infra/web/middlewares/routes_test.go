@@ -0,0 +1,78 @@
+package middlewares
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/GeovaneCavalcante/rate-limit-api/configs"
+	"github.com/GeovaneCavalcante/rate-limit-api/pkg/ratelimit"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRoutePolicies(t *testing.T) {
+	limit := configs.IPConfigLimit{MaxRequests: 5, WindowSecond: 60}
+	policies, err := NewRoutePolicies([]configs.RoutePolicy{
+		{Method: "post", Path: "/login", IPConfigLimit: limit},
+		{Path: "/users/{id}/orders", IPConfigLimit: limit},
+		{Method: "GET", Path: "/search/", IPConfigLimit: limit},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		method   string
+		path     string
+		expected string
+	}{
+		{method: "POST", path: "/login", expected: "POST /login"},
+		{method: "POST", path: "/login/", expected: "POST /login"},
+		{method: "GET", path: "/login"},
+		{method: "POST", path: "/login/reset"},
+		{method: "DELETE", path: "/users/42/orders", expected: "* /users/{id}/orders"},
+		{method: "GET", path: "/users/42"},
+		{method: "GET", path: "/search", expected: "GET /search/"},
+		{method: "GET", path: "/search/books/1", expected: "GET /search/"},
+		{method: "GET", path: "/health"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.method+" "+tt.path, func(t *testing.T) {
+			req, err := http.NewRequest(tt.method, tt.path, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			policy := policies.Match(req)
+			if tt.expected == "" {
+				assert.Nil(t, policy)
+				return
+			}
+			assert.Equal(t, tt.expected+":ip:1.2.3.4", policy.Key("ip:1.2.3.4"))
+			assert.Equal(t, &ratelimit.Options{MaxInInterval: 5, Interval: time.Minute}, policy.Options)
+		})
+	}
+
+	t.Run("should return an error when a policy is invalid", func(t *testing.T) {
+		_, err := NewRoutePolicies([]configs.RoutePolicy{{Path: "login", IPConfigLimit: limit}})
+		assert.ErrorContains(t, err, "must have a path starting with /")
+
+		_, err = NewRoutePolicies([]configs.RoutePolicy{{Path: "/login"}})
+		assert.ErrorContains(t, err, "must set max_requests and window")
+
+		_, err = NewRoutePolicies([]configs.RoutePolicy{{Path: "/login", IPConfigLimit: configs.IPConfigLimit{MaxRequests: 5}}})
+		assert.ErrorContains(t, err, "must set max_requests and window")
+
+		_, err = NewRoutePolicies([]configs.RoutePolicy{{Path: "/export", Cost: 10001}})
+		assert.ErrorContains(t, err, "must have a cost between 0 and 10000")
+	})
+
+	t.Run("should accept a policy that only sets the cost", func(t *testing.T) {
+		policies, err := NewRoutePolicies([]configs.RoutePolicy{{Path: "/export", Cost: 10}})
+		if assert.NoError(t, err) {
+			assert.Nil(t, policies[0].Options)
+			assert.Equal(t, int64(10), policies[0].Cost)
+		}
+	})
+}
@@ -0,0 +1,96 @@
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// BlockStorageInterface persists the block markers, BlockedUntil returns the
+// zero time when the key is not blocked.
+type BlockStorageInterface interface {
+	Block(ctx context.Context, key string, until time.Time) error
+	BlockedUntil(ctx context.Context, key string) (time.Time, error)
+}
+
+// BlockingLimiter locks a key out for BlockDuration once the wrapped
+// limiter rejects it, no matter how the window of the wrapped limiter slides.
+// Without a block duration it only delegates to the wrapped limiter.
+type BlockingLimiter struct {
+	RateLimiter  RateLimiterInterface
+	BlockStorage BlockStorageInterface
+	Clock        Clock
+	Options
+}
+
+func NewBlockingLimiter(rl RateLimiterInterface, bs BlockStorageInterface, ns string, max int64, inter, block time.Duration, opts ...Option) (*BlockingLimiter, error) {
+	s := newSettings(opts)
+
+	return &BlockingLimiter{
+		RateLimiter:  rl,
+		BlockStorage: bs,
+		Clock:        s.clock,
+		Options: Options{
+			NameSpace:     ns,
+			MaxInInterval: max,
+			Interval:      inter,
+			BlockDuration: block,
+		},
+	}, nil
+}
+
+func (bl *BlockingLimiter) Limiter(ctx context.Context, key string, opt *Options) (bool, error) {
+	return limited(bl.Decide(ctx, key, opt))
+}
+
+func (bl *BlockingLimiter) Decide(ctx context.Context, key string, opt *Options) (*Decision, error) {
+
+	nameSpace := chooseString(bl.NameSpace, opt, func(o *Options) string { return o.NameSpace })
+	blockDuration := chooseDuration(bl.BlockDuration, opt, func(o *Options) time.Duration { return o.BlockDuration })
+
+	if blockDuration <= 0 {
+		return bl.RateLimiter.Decide(ctx, key, opt)
+	}
+
+	blockKey := fmt.Sprintf("block:%s:%s", nameSpace, key)
+
+	blockedUntil, err := bl.BlockStorage.BlockedUntil(ctx, blockKey)
+	if err != nil {
+		return nil, fmt.Errorf("error when checking the block: %w", err)
+	}
+
+	current := now(bl.Clock)
+	if current.Before(blockedUntil) {
+		maxInInterval := chooseInt64(bl.MaxInInterval, opt, func(o *Options) int64 { return o.MaxInInterval })
+		interval := chooseDuration(bl.Interval, opt, func(o *Options) time.Duration { return o.Interval })
+
+		return &Decision{
+			Allowed:    false,
+			Limit:      maxInInterval,
+			Window:     interval,
+			Remaining:  0,
+			ResetAt:    blockedUntil,
+			RetryAfter: blockedUntil.Sub(current),
+			NameSpace:  nameSpace,
+			Key:        key,
+		}, nil
+	}
+
+	decision, err := bl.RateLimiter.Decide(ctx, key, opt)
+	if err != nil {
+		return nil, err
+	}
+	if decision.Allowed {
+		return decision, nil
+	}
+
+	blockedUntil = current.Add(blockDuration)
+	err = bl.BlockStorage.Block(ctx, blockKey, blockedUntil)
+	if err != nil {
+		return nil, fmt.Errorf("error when blocking the key: %w", err)
+	}
+
+	decision.ResetAt = blockedUntil
+	decision.RetryAfter = blockedUntil.Sub(current)
+	return decision, nil
+}
@@ -0,0 +1,131 @@
+package ratelimit_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/GeovaneCavalcante/rate-limit-api/pkg/ratelimit"
+	mock_storage "github.com/GeovaneCavalcante/rate-limit-api/pkg/ratelimit/mock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+	"go.uber.org/mock/gomock"
+)
+
+type BlockingTestSuite struct {
+	suite.Suite
+	RateLimiterMock  *mock_storage.MockRateLimiterInterface
+	BlockStorageMock *mock_storage.MockBlockStorageInterface
+}
+
+func (suite *BlockingTestSuite) SetupTest() {
+	ctrl := gomock.NewController(suite.T())
+	suite.RateLimiterMock = mock_storage.NewMockRateLimiterInterface(ctrl)
+	suite.BlockStorageMock = mock_storage.NewMockBlockStorageInterface(ctrl)
+}
+
+func (suite *BlockingTestSuite) TestDecide() {
+	suite.Run("should only delegate when there is no block duration", func() {
+		suite.RateLimiterMock.EXPECT().Decide(gomock.Any(), "key", gomock.Any()).Return(&ratelimit.Decision{Allowed: false}, nil)
+
+		bl, err := ratelimit.NewBlockingLimiter(suite.RateLimiterMock, suite.BlockStorageMock, "test", 5, 60*time.Second, 0)
+		if err != nil {
+			suite.FailNow(err.Error())
+		}
+
+		value, err := bl.Limiter(context.Background(), "key", nil)
+		assert.NoError(suite.T(), err)
+		assert.True(suite.T(), value)
+	})
+
+	suite.Run("should reject without calling the limiter while the key is blocked", func() {
+		blockedUntil := time.Now().Add(10 * time.Minute)
+		suite.BlockStorageMock.EXPECT().BlockedUntil(gomock.Any(), "block:test:key").Return(blockedUntil, nil)
+
+		bl, err := ratelimit.NewBlockingLimiter(suite.RateLimiterMock, suite.BlockStorageMock, "test", 5, 60*time.Second, 30*time.Minute)
+		if err != nil {
+			suite.FailNow(err.Error())
+		}
+
+		decision, err := bl.Decide(context.Background(), "key", nil)
+		assert.NoError(suite.T(), err)
+		assert.False(suite.T(), decision.Allowed)
+		assert.Equal(suite.T(), int64(5), decision.Limit)
+		assert.Equal(suite.T(), 60*time.Second, decision.Window)
+		assert.Equal(suite.T(), blockedUntil, decision.ResetAt)
+		assert.InDelta(suite.T(), float64(10*time.Minute), float64(decision.RetryAfter), float64(time.Second))
+	})
+
+	suite.Run("should allow when the limiter allows", func() {
+		suite.BlockStorageMock.EXPECT().BlockedUntil(gomock.Any(), gomock.Any()).Return(time.Time{}, nil)
+		suite.RateLimiterMock.EXPECT().Decide(gomock.Any(), gomock.Any(), gomock.Any()).Return(&ratelimit.Decision{Allowed: true, Remaining: 3}, nil)
+
+		bl, err := ratelimit.NewBlockingLimiter(suite.RateLimiterMock, suite.BlockStorageMock, "test", 5, 60*time.Second, 30*time.Minute)
+		if err != nil {
+			suite.FailNow(err.Error())
+		}
+
+		decision, err := bl.Decide(context.Background(), "key", nil)
+		assert.NoError(suite.T(), err)
+		assert.True(suite.T(), decision.Allowed)
+		assert.Equal(suite.T(), int64(3), decision.Remaining)
+	})
+
+	suite.Run("should block the key for the block duration of the options when the limiter rejects", func() {
+		suite.BlockStorageMock.EXPECT().BlockedUntil(gomock.Any(), "block:token:key").Return(time.Time{}, nil)
+		suite.RateLimiterMock.EXPECT().Decide(gomock.Any(), gomock.Any(), gomock.Any()).Return(&ratelimit.Decision{Allowed: false, RetryAfter: time.Second}, nil)
+		suite.BlockStorageMock.EXPECT().Block(gomock.Any(), "block:token:key", gomock.Any()).
+			DoAndReturn(func(_ context.Context, _ string, until time.Time) error {
+				assert.WithinDuration(suite.T(), time.Now().Add(30*time.Minute), until, time.Second)
+				return nil
+			})
+
+		bl, err := ratelimit.NewBlockingLimiter(suite.RateLimiterMock, suite.BlockStorageMock, "test", 0, 0, 0)
+		if err != nil {
+			suite.FailNow(err.Error())
+		}
+
+		decision, err := bl.Decide(context.Background(), "key", &ratelimit.Options{
+			NameSpace:     "token",
+			MaxInInterval: 5,
+			Interval:      time.Minute,
+			BlockDuration: 30 * time.Minute,
+		})
+		assert.NoError(suite.T(), err)
+		assert.False(suite.T(), decision.Allowed)
+		assert.Equal(suite.T(), 30*time.Minute, decision.RetryAfter)
+	})
+
+	suite.Run("should return an error when checking the block fails", func() {
+		suite.BlockStorageMock.EXPECT().BlockedUntil(gomock.Any(), gomock.Any()).Return(time.Time{}, errors.New("error"))
+
+		bl, err := ratelimit.NewBlockingLimiter(suite.RateLimiterMock, suite.BlockStorageMock, "test", 5, 60*time.Second, 30*time.Minute)
+		if err != nil {
+			suite.FailNow(err.Error())
+		}
+
+		value, err := bl.Limiter(context.Background(), "key", nil)
+		assert.Equal(suite.T(), "error when checking the block: error", err.Error())
+		assert.False(suite.T(), value)
+	})
+
+	suite.Run("should return an error when blocking the key fails", func() {
+		suite.BlockStorageMock.EXPECT().BlockedUntil(gomock.Any(), gomock.Any()).Return(time.Time{}, nil)
+		suite.RateLimiterMock.EXPECT().Decide(gomock.Any(), gomock.Any(), gomock.Any()).Return(&ratelimit.Decision{Allowed: false}, nil)
+		suite.BlockStorageMock.EXPECT().Block(gomock.Any(), gomock.Any(), gomock.Any()).Return(errors.New("error"))
+
+		bl, err := ratelimit.NewBlockingLimiter(suite.RateLimiterMock, suite.BlockStorageMock, "test", 5, 60*time.Second, 30*time.Minute)
+		if err != nil {
+			suite.FailNow(err.Error())
+		}
+
+		value, err := bl.Limiter(context.Background(), "key", nil)
+		assert.Equal(suite.T(), "error when blocking the key: error", err.Error())
+		assert.False(suite.T(), value)
+	})
+}
+
+func TestBlockingSuite(t *testing.T) {
+	suite.Run(t, new(BlockingTestSuite))
+}
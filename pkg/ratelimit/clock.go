@@ -0,0 +1,50 @@
+package ratelimit
+
+import "time"
+
+// Clock is the source of the timestamps used by the limiters.
+type Clock interface {
+	Now() time.Time
+}
+
+type realClock struct{}
+
+func (realClock) Now() time.Time {
+	return time.Now()
+}
+
+// RealClock returns the Clock backed by time.Now used by default.
+func RealClock() Clock {
+	return realClock{}
+}
+
+type settings struct {
+	clock Clock
+}
+
+// Option configures the optional settings of the limiter constructors.
+type Option func(*settings)
+
+// WithClock replaces the real clock, mostly to control time in tests.
+func WithClock(c Clock) Option {
+	return func(s *settings) {
+		s.clock = c
+	}
+}
+
+func newSettings(opts []Option) settings {
+	s := settings{clock: RealClock()}
+	for _, opt := range opts {
+		opt(&s)
+	}
+	return s
+}
+
+// now reads c, falling back to the real clock for limiters built without a
+// constructor.
+func now(c Clock) time.Time {
+	if c == nil {
+		return time.Now()
+	}
+	return c.Now()
+}
@@ -0,0 +1,133 @@
+package ratelimit_test
+
+import (
+	"context"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/GeovaneCavalcante/rate-limit-api/pkg/ratelimit"
+	"github.com/GeovaneCavalcante/rate-limit-api/pkg/ratelimit/clocktest"
+	"github.com/GeovaneCavalcante/rate-limit-api/pkg/ratelimit/memory"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCost(t *testing.T) {
+	start := time.Unix(1700000000, 0)
+
+	limiters := map[string]func(clock ratelimit.Clock) ratelimit.RateLimiterInterface{
+		"sliding log": func(clock ratelimit.Clock) ratelimit.RateLimiterInterface {
+			rl, _ := ratelimit.New(memory.NewMemoryEventStorage(0, memory.WithClock(clock)), "test", 10, 10*time.Second, ratelimit.WithClock(clock))
+			return rl
+		},
+		"sliding log without atomic storage": func(clock ratelimit.Clock) ratelimit.RateLimiterInterface {
+			rl, _ := ratelimit.New(eventStorage{memory.NewMemoryEventStorage(0, memory.WithClock(clock))}, "test", 10, 10*time.Second, ratelimit.WithClock(clock))
+			return rl
+		},
+		"token bucket": func(clock ratelimit.Clock) ratelimit.RateLimiterInterface {
+			rl, _ := ratelimit.NewTokenBucketLimiter(memory.NewMemoryTokenBucketStorage(0, memory.WithClock(clock)), "test", 10, 10*time.Second, 0, ratelimit.WithClock(clock))
+			return rl
+		},
+		"sliding window": func(clock ratelimit.Clock) ratelimit.RateLimiterInterface {
+			rl, _ := ratelimit.NewSlidingWindowLimiter(memory.NewMemoryCounterStorage(0, memory.WithClock(clock)), "test", 10, 10*time.Second, ratelimit.WithClock(clock))
+			return rl
+		},
+		"gcra": func(clock ratelimit.Clock) ratelimit.RateLimiterInterface {
+			rl, _ := ratelimit.NewGCRALimiter(memory.NewMemoryTATStorage(0, memory.WithClock(clock)), "test", 10, 10*time.Second, 10, ratelimit.WithClock(clock))
+			return rl
+		},
+	}
+
+	for name, newLimiter := range limiters {
+		t.Run(name+" should consume the cost of the request", func(t *testing.T) {
+			rl := newLimiter(clocktest.NewFakeClock(start))
+
+			decide := func(cost int64) *ratelimit.Decision {
+				decision, err := rl.Decide(context.Background(), "key", &ratelimit.Options{Cost: cost})
+				if err != nil {
+					t.Fatal(err)
+				}
+				return decision
+			}
+
+			decision := decide(4)
+			assert.True(t, decision.Allowed)
+			assert.Equal(t, int64(6), decision.Remaining)
+
+			decision = decide(4)
+			assert.True(t, decision.Allowed)
+			assert.Equal(t, int64(2), decision.Remaining)
+
+			decision = decide(4)
+			assert.False(t, decision.Allowed)
+			assert.Greater(t, decision.RetryAfter, time.Duration(0))
+
+			decision = decide(2)
+			assert.True(t, decision.Allowed)
+			assert.Equal(t, int64(0), decision.Remaining)
+
+			assert.False(t, decide(0).Allowed)
+		})
+	}
+
+	for name, newLimiter := range limiters {
+		t.Run(name+" should deny a cost above the limit without consuming it", func(t *testing.T) {
+			rl := newLimiter(clocktest.NewFakeClock(start))
+
+			for _, cost := range []int64{11, ratelimit.MaxCost + 1, math.MaxInt64 / 2, math.MaxInt64} {
+				decision, err := rl.Decide(context.Background(), "key", &ratelimit.Options{Cost: cost})
+				if assert.NoError(t, err, cost) {
+					assert.False(t, decision.Allowed, cost)
+					assert.Greater(t, decision.RetryAfter, time.Duration(0), cost)
+				}
+			}
+
+			decision, err := rl.Decide(context.Background(), "key", &ratelimit.Options{Cost: 10})
+			if assert.NoError(t, err) {
+				assert.True(t, decision.Allowed)
+				assert.Equal(t, int64(0), decision.Remaining)
+			}
+
+			decision, err = rl.Decide(context.Background(), "key", nil)
+			if assert.NoError(t, err) {
+				assert.False(t, decision.Allowed)
+			}
+		})
+	}
+
+	t.Run("gcra should not overflow the theoretical arrival time", func(t *testing.T) {
+		clock := clocktest.NewFakeClock(start)
+		rl, _ := ratelimit.NewGCRALimiter(memory.NewMemoryTATStorage(0, memory.WithClock(clock)), "test", 5, time.Minute, math.MaxInt64, ratelimit.WithClock(clock))
+
+		for i := 0; i < 3; i++ {
+			decision, err := rl.Decide(context.Background(), "key", &ratelimit.Options{Cost: math.MaxInt64 / 2})
+			if assert.NoError(t, err) {
+				assert.False(t, decision.Allowed)
+			}
+		}
+
+		decision, err := rl.Decide(context.Background(), "key", &ratelimit.Options{Cost: ratelimit.MaxCost})
+		if assert.NoError(t, err) {
+			assert.True(t, decision.Allowed)
+		}
+	})
+
+	t.Run("sliding log should wait for enough events to leave the window", func(t *testing.T) {
+		for _, name := range []string{"sliding log", "sliding log without atomic storage"} {
+			clock := clocktest.NewFakeClock(start)
+			rl := limiters[name](clock)
+
+			for i := 0; i < 5; i++ {
+				_, err := rl.Decide(context.Background(), "key", &ratelimit.Options{Cost: 2})
+				assert.NoError(t, err)
+				clock.Advance(time.Second)
+			}
+
+			decision, err := rl.Decide(context.Background(), "key", &ratelimit.Options{Cost: 3})
+			assert.NoError(t, err)
+			assert.False(t, decision.Allowed, name)
+			assert.Equal(t, 6*time.Second, decision.RetryAfter, name)
+			assert.Equal(t, start.Add(10*time.Second), decision.ResetAt, name)
+		}
+	})
+}
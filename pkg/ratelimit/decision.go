@@ -0,0 +1,62 @@
+package ratelimit
+
+import "time"
+
+// Decision is the outcome of a rate limit check.
+type Decision struct {
+	Allowed bool
+	// Limit is the number of requests allowed in the window or, for the
+	// token bucket and GCRA, the burst.
+	Limit int64
+	// Window is the period Limit applies to.
+	Window    time.Duration
+	Remaining int64
+	// ResetAt is when the key is back to its full limit.
+	ResetAt time.Time
+	// RetryAfter is how long to wait before the next request is allowed, it
+	// is zero when the request is allowed.
+	RetryAfter time.Duration
+	NameSpace  string
+	Key        string
+	// Rule is the rule that matched the request, such as the "route",
+	// "token" or "ip" check of the HTTP middleware or the key of the Envoy
+	// descriptor. It is set by the caller that chose the limiter and is
+	// empty otherwise.
+	Rule string
+}
+
+// MostRestrictive returns the decision to report when several limits apply to
+// the same request: the rejection with the longest RetryAfter or, when every
+// limit allows the request, the one with the fewest remaining requests.
+func MostRestrictive(decisions ...*Decision) *Decision {
+	var result *Decision
+
+	for _, d := range decisions {
+		switch {
+		case d == nil:
+		case result == nil:
+			result = d
+		case result.Allowed != d.Allowed:
+			if !d.Allowed {
+				result = d
+			}
+		case !d.Allowed:
+			if d.RetryAfter > result.RetryAfter {
+				result = d
+			}
+		case d.Remaining < result.Remaining:
+			result = d
+		}
+	}
+
+	return result
+}
+
+// limited adapts a Decision to the Limiter contract, which returns true when
+// the request must be blocked.
+func limited(d *Decision, err error) (bool, error) {
+	if err != nil {
+		return false, err
+	}
+	return !d.Allowed, nil
+}
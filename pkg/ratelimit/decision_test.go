@@ -0,0 +1,32 @@
+package ratelimit_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/GeovaneCavalcante/rate-limit-api/pkg/ratelimit"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMostRestrictive(t *testing.T) {
+	allowedMany := &ratelimit.Decision{Allowed: true, Remaining: 10}
+	allowedFew := &ratelimit.Decision{Allowed: true, Remaining: 1}
+	deniedSoon := &ratelimit.Decision{RetryAfter: time.Second}
+	deniedLater := &ratelimit.Decision{RetryAfter: time.Hour}
+
+	t.Run("should return the decision with fewer remaining requests when all allow", func(t *testing.T) {
+		assert.Same(t, allowedFew, ratelimit.MostRestrictive(allowedMany, allowedFew))
+	})
+
+	t.Run("should return the rejection over the allowed decisions", func(t *testing.T) {
+		assert.Same(t, deniedSoon, ratelimit.MostRestrictive(allowedFew, deniedSoon, allowedMany))
+	})
+
+	t.Run("should return the rejection with the longest retry", func(t *testing.T) {
+		assert.Same(t, deniedLater, ratelimit.MostRestrictive(deniedSoon, deniedLater, allowedFew))
+	})
+
+	t.Run("should return nil without decisions", func(t *testing.T) {
+		assert.Nil(t, ratelimit.MostRestrictive())
+	})
+}
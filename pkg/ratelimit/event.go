@@ -2,6 +2,8 @@ package ratelimit
 
 import (
 	"context"
+	"strconv"
+	"strings"
 	"time"
 )
 
@@ -11,6 +13,21 @@ type Event struct {
 	Value string
 }
 
+// EventWeight is the number of units an event consumes. A request with a
+// cost above 1 is stored as a single event whose value ends with its cost,
+// such as "event:<id>:<timestamp>:<cost>", the other events weigh 1.
+func EventWeight(value string) int64 {
+	parts := strings.Split(value, ":")
+	if len(parts) != 4 || parts[0] != "event" {
+		return 1
+	}
+	weight, err := strconv.ParseUint(parts[3], 10, 63)
+	if err != nil || weight < 1 {
+		return 1
+	}
+	return int64(weight)
+}
+
 type EventStorageInterface interface {
 	CountRange(ctx context.Context, key, min, max string) (int64, error)
 	FindRangeWithScores(ctx context.Context, key string, start, stop int64) ([]*Event, error)
@@ -18,3 +35,24 @@ type EventStorageInterface interface {
 	Add(ctx context.Context, key string, events ...*Event) ([]*Event, error)
 	SetEventTLL(ctx context.Context, key string, ttl time.Duration) error
 }
+
+// EventWindow is the state of a key after an AddWithinLimit call.
+type EventWindow struct {
+	// Count is the weight of the events in the window.
+	Count       int64
+	Added       bool
+	OldestScore float64
+	// RetryScore is the score of the event that must leave the window
+	// before the rejected events fit in the limit.
+	RetryScore float64
+}
+
+// AtomicEventStorage is an optional capability of an EventStorageInterface.
+// AddWithinLimit must, as a single atomic operation, remove the events with a
+// score lower than or equal to windowStart, sum the EventWeight of the
+// remaining ones, add all the events only when their weight fits in limit and
+// refresh the key TTL.
+type AtomicEventStorage interface {
+	EventStorageInterface
+	AddWithinLimit(ctx context.Context, key string, events []*Event, windowStart float64, limit int64, ttl time.Duration) (*EventWindow, error)
+}
@@ -0,0 +1,127 @@
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"time"
+)
+
+// TATStorageInterface persists the theoretical arrival time (TAT) of each key
+// as Unix nanoseconds. TakeTAT must, as a single atomic operation, take the
+// TAT as the later of the stored one, 0 when the key does not exist, and now,
+// and only when TAT + increment does not exceed limit store it with a TTL
+// lasting until then. It returns the TAT before the increment and whether
+// it was stored.
+type TATStorageInterface interface {
+	TakeTAT(ctx context.Context, key string, now, increment, limit int64) (int64, bool, error)
+}
+
+// GCRALimiter implements the generic cell rate algorithm, it paces the
+// requests one emission interval (Interval / MaxInInterval) apart and
+// tolerates Burst requests ahead of that pace. Burst defaults to 1.
+type GCRALimiter struct {
+	TATStorage TATStorageInterface
+	Clock      Clock
+	Options
+}
+
+func NewGCRALimiter(ts TATStorageInterface, ns string, max int64, inter time.Duration, burst int64, opts ...Option) (*GCRALimiter, error) {
+	s := newSettings(opts)
+
+	return &GCRALimiter{
+		TATStorage: ts,
+		Clock:      s.clock,
+		Options:    Options{NameSpace: ns, MaxInInterval: max, Interval: inter, Burst: burst},
+	}, nil
+}
+
+func (g *GCRALimiter) Limiter(ctx context.Context, key string, opt *Options) (bool, error) {
+	return limited(g.Decide(ctx, key, opt))
+}
+
+func (g *GCRALimiter) Decide(ctx context.Context, key string, opt *Options) (*Decision, error) {
+
+	nameSpace := chooseString(g.NameSpace, opt, func(o *Options) string { return o.NameSpace })
+	maxInInterval := chooseInt64(g.MaxInInterval, opt, func(o *Options) int64 { return o.MaxInInterval })
+	interval := chooseDuration(g.Interval, opt, func(o *Options) time.Duration { return o.Interval })
+	burst := chooseInt64(g.Burst, opt, func(o *Options) int64 { return o.Burst })
+	cost := requestCost(opt)
+
+	if maxInInterval <= 0 || interval <= 0 {
+		return nil, fmt.Errorf("invalid gcra rate: %d requests in %s", maxInInterval, interval)
+	}
+	if burst <= 0 {
+		burst = 1
+	}
+
+	emissionInterval := interval / time.Duration(maxInInterval)
+	if emissionInterval <= 0 {
+		return nil, fmt.Errorf("invalid gcra rate: %d requests in %s is more than one per nanosecond", maxInInterval, interval)
+	}
+	burstOffset := time.Duration(saturatingMul(int64(emissionInterval), burst))
+
+	bucketName := fmt.Sprintf("%s:%s", nameSpace, key)
+
+	if exceedsLimit(cost, burst) {
+		return &Decision{
+			Limit:      burst,
+			Window:     burstOffset,
+			ResetAt:    now(g.Clock),
+			RetryAfter: burstOffset,
+			NameSpace:  nameSpace,
+			Key:        key,
+		}, nil
+	}
+
+	current := now(g.Clock).UnixNano()
+	increment := saturatingMul(int64(emissionInterval), cost)
+	limit := saturatingAdd(current, int64(burstOffset))
+
+	tat, allowed, err := g.TATStorage.TakeTAT(ctx, bucketName, current, increment, limit)
+	if err != nil {
+		return nil, fmt.Errorf("error when taking the theoretical arrival time: %w", err)
+	}
+	newTAT := saturatingAdd(tat, increment)
+
+	if !allowed {
+		return &Decision{
+			Allowed:    false,
+			Limit:      burst,
+			Window:     burstOffset,
+			Remaining:  0,
+			ResetAt:    time.Unix(0, tat),
+			RetryAfter: time.Duration(max(0, newTAT-limit)),
+			NameSpace:  nameSpace,
+			Key:        key,
+		}, nil
+	}
+
+	return &Decision{
+		Allowed:   true,
+		Limit:     burst,
+		Window:    burstOffset,
+		Remaining: (limit - newTAT) / int64(emissionInterval),
+		ResetAt:   time.Unix(0, newTAT),
+		NameSpace: nameSpace,
+		Key:       key,
+	}, nil
+}
+
+// saturatingMul multiplies non-negative numbers, returning math.MaxInt64
+// instead of overflowing.
+func saturatingMul(a, b int64) int64 {
+	if a != 0 && b > math.MaxInt64/a {
+		return math.MaxInt64
+	}
+	return a * b
+}
+
+// saturatingAdd adds non-negative numbers, returning math.MaxInt64 instead of
+// overflowing.
+func saturatingAdd(a, b int64) int64 {
+	if b > math.MaxInt64-a {
+		return math.MaxInt64
+	}
+	return a + b
+}
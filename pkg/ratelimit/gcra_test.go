@@ -0,0 +1,166 @@
+package ratelimit_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/GeovaneCavalcante/rate-limit-api/pkg/ratelimit"
+	"github.com/GeovaneCavalcante/rate-limit-api/pkg/ratelimit/clocktest"
+	"github.com/GeovaneCavalcante/rate-limit-api/pkg/ratelimit/memory"
+	mock_storage "github.com/GeovaneCavalcante/rate-limit-api/pkg/ratelimit/mock"
+	ratelimitredis "github.com/GeovaneCavalcante/rate-limit-api/pkg/ratelimit/redis"
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-redis/redis/v8"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+	"go.uber.org/mock/gomock"
+)
+
+type GCRATestSuite struct {
+	suite.Suite
+	TATStorageMock *mock_storage.MockTATStorageInterface
+}
+
+func (suite *GCRATestSuite) SetupTest() {
+	ctrl := gomock.NewController(suite.T())
+	suite.TATStorageMock = mock_storage.NewMockTATStorageInterface(ctrl)
+}
+
+func (suite *GCRATestSuite) TestDecide() {
+	suite.Run("should allow the first request and store the next arrival time", func() {
+		suite.TATStorageMock.EXPECT().TakeTAT(gomock.Any(), "test:key", gomock.Any(), int64(time.Second), gomock.Any()).
+			DoAndReturn(func(_ context.Context, _ string, now, _, limit int64) (int64, bool, error) {
+				assert.InDelta(suite.T(), time.Now().UnixNano(), now, float64(100*time.Millisecond))
+				assert.Equal(suite.T(), now+int64(3*time.Second), limit)
+				return now, true, nil
+			})
+
+		g, err := ratelimit.NewGCRALimiter(suite.TATStorageMock, "test", 60, 60*time.Second, 3)
+		if err != nil {
+			suite.FailNow(err.Error())
+		}
+
+		result, err := g.Decide(context.Background(), "key", nil)
+		assert.NoError(suite.T(), err)
+		assert.True(suite.T(), result.Allowed)
+		assert.Equal(suite.T(), int64(3), result.Limit)
+		assert.Equal(suite.T(), int64(2), result.Remaining)
+		assert.Equal(suite.T(), time.Duration(0), result.RetryAfter)
+		assert.Equal(suite.T(), "test", result.NameSpace)
+		assert.Equal(suite.T(), "key", result.Key)
+	})
+
+	suite.Run("should deny the request and compute the retry after", func() {
+		tat := time.Now().Add(10 * time.Second).UnixNano()
+		suite.TATStorageMock.EXPECT().TakeTAT(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(tat, false, nil)
+
+		g, err := ratelimit.NewGCRALimiter(suite.TATStorageMock, "test", 60, 60*time.Second, 1)
+		if err != nil {
+			suite.FailNow(err.Error())
+		}
+
+		result, err := g.Decide(context.Background(), "key", nil)
+		assert.NoError(suite.T(), err)
+		assert.False(suite.T(), result.Allowed)
+		assert.Equal(suite.T(), int64(0), result.Remaining)
+		assert.InDelta(suite.T(), float64(10*time.Second), float64(result.RetryAfter), float64(100*time.Millisecond))
+		assert.Equal(suite.T(), time.Unix(0, tat), result.ResetAt)
+	})
+
+	suite.Run("should return an error when the rate is invalid", func() {
+		g, err := ratelimit.NewGCRALimiter(suite.TATStorageMock, "test", 0, 0, 0)
+		if err != nil {
+			suite.FailNow(err.Error())
+		}
+
+		value, err := g.Limiter(context.Background(), "key", nil)
+		assert.Equal(suite.T(), "invalid gcra rate: 0 requests in 0s", err.Error())
+		assert.False(suite.T(), value)
+	})
+
+	suite.Run("should return an error when the emission interval is zero", func() {
+		g, err := ratelimit.NewGCRALimiter(suite.TATStorageMock, "test", 2000000, time.Millisecond, 0)
+		if err != nil {
+			suite.FailNow(err.Error())
+		}
+
+		decision, err := g.Decide(context.Background(), "key", nil)
+		assert.EqualError(suite.T(), err, "invalid gcra rate: 2000000 requests in 1ms is more than one per nanosecond")
+		assert.Nil(suite.T(), decision)
+	})
+
+	suite.Run("should return an error when the storage fails", func() {
+		suite.TATStorageMock.EXPECT().TakeTAT(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(int64(0), false, errors.New("error"))
+
+		g, err := ratelimit.NewGCRALimiter(suite.TATStorageMock, "test", 1, 60*time.Second, 0)
+		if err != nil {
+			suite.FailNow(err.Error())
+		}
+
+		value, err := g.Limiter(context.Background(), "key", nil)
+		assert.Equal(suite.T(), "error when taking the theoretical arrival time: error", err.Error())
+		assert.False(suite.T(), value)
+	})
+}
+
+func (suite *GCRATestSuite) TestPacing() {
+	suite.Run("should pace the requests one emission interval apart", func() {
+		clock := clocktest.NewFakeClock(time.Unix(1700000000, 0))
+		g, err := ratelimit.NewGCRALimiter(memory.NewMemoryTATStorage(0, memory.WithClock(clock)), "test", 6, 60*time.Second, 1, ratelimit.WithClock(clock))
+		if err != nil {
+			suite.FailNow(err.Error())
+		}
+
+		decision, err := g.Decide(context.Background(), "key", nil)
+		assert.NoError(suite.T(), err)
+		assert.True(suite.T(), decision.Allowed)
+
+		clock.Advance(9 * time.Second)
+		decision, err = g.Decide(context.Background(), "key", nil)
+		assert.NoError(suite.T(), err)
+		assert.False(suite.T(), decision.Allowed)
+		assert.Equal(suite.T(), time.Second, decision.RetryAfter)
+
+		clock.Advance(time.Second)
+		decision, err = g.Decide(context.Background(), "key", nil)
+		assert.NoError(suite.T(), err)
+		assert.True(suite.T(), decision.Allowed)
+	})
+}
+
+func (suite *GCRATestSuite) TestConcurrency() {
+	suite.Run("should allow every request within the burst under concurrency", func() {
+		mr := miniredis.RunT(suite.T())
+		rc := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+		defer rc.Close()
+
+		g, err := ratelimit.NewGCRALimiter(ratelimitredis.NewRedisTATStorage(rc), "test", 1000, time.Hour, 1000)
+		if err != nil {
+			suite.FailNow(err.Error())
+		}
+
+		var allowed atomic.Int64
+		var wg sync.WaitGroup
+		for i := 0; i < 200; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				decision, err := g.Decide(context.Background(), "key", nil)
+				if assert.NoError(suite.T(), err) && decision.Allowed {
+					allowed.Add(1)
+				}
+			}()
+		}
+		wg.Wait()
+
+		assert.Equal(suite.T(), int64(200), allowed.Load())
+	})
+}
+
+func TestGCRASuite(t *testing.T) {
+	suite.Run(t, new(GCRATestSuite))
+}
@@ -0,0 +1,182 @@
+// Package interceptor limits gRPC calls with the rate limiters of the
+// ratelimit package.
+package interceptor
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"net"
+	"strconv"
+	"time"
+
+	"github.com/GeovaneCavalcante/rate-limit-api/pkg/logger"
+	"github.com/GeovaneCavalcante/rate-limit-api/pkg/ratelimit"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/durationpb"
+)
+
+// DefaultAPIKeyMetadata is the metadata key of the api key, the gRPC
+// counterpart of the API_KEY header.
+const DefaultAPIKeyMetadata = "api_key"
+
+const limitReachedMessage = "you have reached the maximum number of requests or actions allowed within a certain time frame"
+
+// Limiter applies the first limit that matches a call, like the HTTP
+// middleware: the limit of the full method name, then the limit of the api
+// key and then the limit of the peer address.
+type Limiter struct {
+	TokenLimiter ratelimit.RateLimiterInterface
+	IPLimiter    ratelimit.RateLimiterInterface
+	// TokenOptions returns the limit of an api key, or nil when the key is
+	// unknown and the call must be rejected.
+	TokenOptions func(token string) *ratelimit.Options
+	// MethodLimiter enforces the MethodOptions, keyed by full method name
+	// such as "/orders.v1.Orders/Create", in place of the token and ip
+	// limits.
+	MethodLimiter ratelimit.RateLimiterInterface
+	MethodOptions map[string]*ratelimit.Options
+	// APIKeyMetadata is the metadata key of the api key, DefaultAPIKeyMetadata
+	// when empty.
+	APIKeyMetadata string
+}
+
+func NewLimiter(tokenLimiter, ipLimiter ratelimit.RateLimiterInterface, tokenOptions func(token string) *ratelimit.Options) *Limiter {
+	return &Limiter{
+		TokenLimiter: tokenLimiter,
+		IPLimiter:    ipLimiter,
+		TokenOptions: tokenOptions,
+	}
+}
+
+// UnaryServerInterceptor rejects the calls over the limit with
+// codes.ResourceExhausted before the handler runs.
+func (l *Limiter) UnaryServerInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		trailer, err := l.allow(ctx, info.FullMethod)
+		if trailer != nil {
+			grpc.SetTrailer(ctx, trailer)
+		}
+		if err != nil {
+			return nil, err
+		}
+		return handler(ctx, req)
+	}
+}
+
+// StreamServerInterceptor limits the opening of the streams, the messages
+// of an open stream are not counted.
+func (l *Limiter) StreamServerInterceptor() grpc.StreamServerInterceptor {
+	return func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		trailer, err := l.allow(ss.Context(), info.FullMethod)
+		if trailer != nil {
+			ss.SetTrailer(trailer)
+		}
+		if err != nil {
+			return err
+		}
+		return handler(srv, ss)
+	}
+}
+
+// allow returns the RateLimit trailer of the decision and the status error
+// of a rejected call.
+func (l *Limiter) allow(ctx context.Context, fullMethod string) (metadata.MD, error) {
+	token := l.apiKey(ctx)
+	addr := peerAddress(ctx)
+
+	var tokenOptions *ratelimit.Options
+	if token != "" {
+		if l.TokenOptions != nil {
+			tokenOptions = l.TokenOptions(token)
+		}
+		if tokenOptions == nil {
+			logger.Error(fmt.Sprintf("token %s not found", token), nil)
+			return nil, status.Error(codes.Unauthenticated, "token not found")
+		}
+	}
+
+	kind, limiter, key := "ip", l.IPLimiter, addr
+	var opt *ratelimit.Options
+	methodOptions, limitedMethod := l.MethodOptions[fullMethod]
+	switch {
+	case limitedMethod && l.MethodLimiter != nil:
+		client := "ip:" + addr
+		if token != "" {
+			client = "token:" + token
+		}
+		kind, limiter, key, opt = "method", l.MethodLimiter, fullMethod+":"+client, methodOptions
+	case token != "":
+		kind, limiter, key, opt = "token", l.TokenLimiter, token, tokenOptions
+	}
+
+	decision, err := limiter.Decide(ctx, key, opt)
+	if err != nil {
+		logger.Error(fmt.Sprintf("error when executing the RateLimiter by %s", kind), err)
+		return nil, status.Error(codes.Internal, "error when executing the RateLimiter")
+	}
+
+	trailer := rateLimitTrailer(decision)
+	if decision.Allowed {
+		return trailer, nil
+	}
+
+	logger.Warn(fmt.Sprintf("%s limit reached for %s", kind, fullMethod), nil)
+	st := status.New(codes.ResourceExhausted, limitReachedMessage)
+	if detailed, err := st.WithDetails(&errdetails.RetryInfo{RetryDelay: durationpb.New(decision.RetryAfter)}); err == nil {
+		st = detailed
+	}
+	return trailer, st.Err()
+}
+
+func (l *Limiter) apiKey(ctx context.Context) string {
+	key := l.APIKeyMetadata
+	if key == "" {
+		key = DefaultAPIKeyMetadata
+	}
+
+	if values := metadata.ValueFromIncomingContext(ctx, key); len(values) > 0 {
+		return values[0]
+	}
+	return ""
+}
+
+// peerAddress is the address of the peer without port.
+func peerAddress(ctx context.Context) string {
+	p, ok := peer.FromContext(ctx)
+	if !ok || p.Addr == nil {
+		return ""
+	}
+
+	addr := p.Addr.String()
+	if host, _, err := net.SplitHostPort(addr); err == nil {
+		return host
+	}
+	return addr
+}
+
+// rateLimitTrailer carries the RateLimit headers of the HTTP middleware and,
+// on rejected calls, retry-after in seconds.
+func rateLimitTrailer(d *ratelimit.Decision) metadata.MD {
+	md := metadata.Pairs(
+		"ratelimit-limit", strconv.FormatInt(d.Limit, 10),
+		"ratelimit-remaining", strconv.FormatInt(max(0, d.Remaining), 10),
+		"ratelimit-reset", strconv.FormatInt(ceilSeconds(time.Until(d.ResetAt)), 10),
+	)
+	if !d.Allowed {
+		md.Set("retry-after", strconv.FormatInt(ceilSeconds(d.RetryAfter), 10))
+	}
+	return md
+}
+
+func ceilSeconds(d time.Duration) int64 {
+	if d <= 0 {
+		return 0
+	}
+	return int64(math.Ceil(d.Seconds()))
+}
@@ -0,0 +1,120 @@
+package interceptor
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/GeovaneCavalcante/rate-limit-api/pkg/ratelimit"
+	mock_ratelimit "github.com/GeovaneCavalcante/rate-limit-api/pkg/ratelimit/mock"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/mock/gomock"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+func newHealthClient(t *testing.T, l *Limiter) healthpb.HealthClient {
+	listener := bufconn.Listen(1 << 20)
+	server := grpc.NewServer(
+		grpc.UnaryInterceptor(l.UnaryServerInterceptor()),
+		grpc.StreamInterceptor(l.StreamServerInterceptor()),
+	)
+	healthpb.RegisterHealthServer(server, health.NewServer())
+	go server.Serve(listener)
+	t.Cleanup(server.Stop)
+
+	conn, err := grpc.Dial("bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return listener.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	return healthpb.NewHealthClient(conn)
+}
+
+func TestInterceptors(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	tokenLimiter := mock_ratelimit.NewMockRateLimiterInterface(ctrl)
+	ipLimiter := mock_ratelimit.NewMockRateLimiterInterface(ctrl)
+	methodLimiter := mock_ratelimit.NewMockRateLimiterInterface(ctrl)
+
+	tokenOptions := &ratelimit.Options{NameSpace: "token", MaxInInterval: 100, Interval: time.Minute}
+	watchOptions := &ratelimit.Options{MaxInInterval: 1, Interval: time.Minute}
+
+	l := NewLimiter(tokenLimiter, ipLimiter, func(token string) *ratelimit.Options {
+		if token == "123" {
+			return tokenOptions
+		}
+		return nil
+	})
+	l.MethodLimiter = methodLimiter
+	l.MethodOptions = map[string]*ratelimit.Options{"/grpc.health.v1.Health/Watch": watchOptions}
+
+	client := newHealthClient(t, l)
+	withToken := metadata.AppendToOutgoingContext(context.Background(), "api_key", "123")
+
+	t.Run("should limit the peer address without api key", func(t *testing.T) {
+		ipLimiter.EXPECT().Decide(gomock.Any(), "bufconn", nil).Return(&ratelimit.Decision{Allowed: true, Limit: 20, Remaining: 19}, nil)
+
+		var trailer metadata.MD
+		_, err := client.Check(context.Background(), &healthpb.HealthCheckRequest{}, grpc.Trailer(&trailer))
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"19"}, trailer.Get("ratelimit-remaining"))
+	})
+
+	t.Run("should reject the api key over its limit with retry info", func(t *testing.T) {
+		tokenLimiter.EXPECT().Decide(gomock.Any(), "123", tokenOptions).Return(&ratelimit.Decision{
+			Allowed:    false,
+			Limit:      100,
+			RetryAfter: 1500 * time.Millisecond,
+		}, nil)
+
+		var trailer metadata.MD
+		_, err := client.Check(withToken, &healthpb.HealthCheckRequest{}, grpc.Trailer(&trailer))
+		assert.Equal(t, codes.ResourceExhausted, status.Code(err))
+		assert.Equal(t, []string{"2"}, trailer.Get("retry-after"))
+
+		details := status.Convert(err).Details()
+		if assert.Len(t, details, 1) {
+			assert.Equal(t, 1500*time.Millisecond, details[0].(*errdetails.RetryInfo).RetryDelay.AsDuration())
+		}
+	})
+
+	t.Run("should reject an unknown api key", func(t *testing.T) {
+		ctx := metadata.AppendToOutgoingContext(context.Background(), "api_key", "unknown")
+
+		_, err := client.Check(ctx, &healthpb.HealthCheckRequest{})
+		assert.Equal(t, codes.Unauthenticated, status.Code(err))
+	})
+
+	t.Run("should limit the streams of a method on their own", func(t *testing.T) {
+		methodLimiter.EXPECT().Decide(gomock.Any(), "/grpc.health.v1.Health/Watch:token:123", watchOptions).Return(&ratelimit.Decision{Allowed: false, RetryAfter: time.Minute}, nil)
+
+		stream, err := client.Watch(withToken, &healthpb.HealthCheckRequest{})
+		if assert.NoError(t, err) {
+			_, err = stream.Recv()
+			assert.Equal(t, codes.ResourceExhausted, status.Code(err))
+			assert.Equal(t, []string{"60"}, stream.Trailer().Get("retry-after"))
+		}
+	})
+
+	t.Run("should return an error when the limiter fails", func(t *testing.T) {
+		ipLimiter.EXPECT().Decide(gomock.Any(), "bufconn", nil).Return(nil, assert.AnError)
+
+		_, err := client.Check(context.Background(), &healthpb.HealthCheckRequest{})
+		assert.Equal(t, codes.Internal, status.Code(err))
+	})
+}
@@ -0,0 +1,40 @@
+package memory
+
+import (
+	"context"
+	"time"
+)
+
+type MemoryBlockStorage struct {
+	*store[time.Time]
+}
+
+func NewMemoryBlockStorage(maxKeys int, opts ...Option) *MemoryBlockStorage {
+	return &MemoryBlockStorage{
+		store: newStore[time.Time](maxKeys, opts),
+	}
+}
+
+func (mbs *MemoryBlockStorage) Block(ctx context.Context, key string, until time.Time) error {
+	mbs.mu.Lock()
+	defer mbs.mu.Unlock()
+
+	if !mbs.now().Before(until) {
+		return nil
+	}
+
+	e := mbs.put(key, until)
+	e.expiresAt = until
+	return nil
+}
+
+func (mbs *MemoryBlockStorage) BlockedUntil(ctx context.Context, key string) (time.Time, error) {
+	mbs.mu.Lock()
+	defer mbs.mu.Unlock()
+
+	e, ok := mbs.get(key, mbs.now())
+	if !ok {
+		return time.Time{}, nil
+	}
+	return e.value, nil
+}
@@ -0,0 +1,28 @@
+package memory
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMemoryBlockStorage(t *testing.T) {
+	ctx := context.Background()
+	s := NewMemoryBlockStorage(0)
+
+	until, err := s.BlockedUntil(ctx, "key")
+	assert.NoError(t, err)
+	assert.True(t, until.IsZero())
+
+	blockedUntil := time.Now().Add(time.Minute)
+	assert.NoError(t, s.Block(ctx, "key", blockedUntil))
+
+	until, _ = s.BlockedUntil(ctx, "key")
+	assert.Equal(t, blockedUntil, until)
+
+	assert.NoError(t, s.Block(ctx, "expired", time.Now().Add(-time.Second)))
+	until, _ = s.BlockedUntil(ctx, "expired")
+	assert.True(t, until.IsZero())
+}
@@ -0,0 +1,78 @@
+package memory
+
+import (
+	"context"
+	"time"
+
+	"github.com/GeovaneCavalcante/rate-limit-api/pkg/ratelimit"
+)
+
+type MemoryCounterStorage struct {
+	*store[int64]
+}
+
+func NewMemoryCounterStorage(maxKeys int, opts ...Option) *MemoryCounterStorage {
+	return &MemoryCounterStorage{
+		store: newStore[int64](maxKeys, opts),
+	}
+}
+
+func (mcs *MemoryCounterStorage) GetCounters(ctx context.Context, keys ...string) ([]int64, error) {
+	mcs.mu.Lock()
+	defer mcs.mu.Unlock()
+
+	now := mcs.now()
+	counters := make([]int64, len(keys))
+	for i, key := range keys {
+		if e, ok := mcs.get(key, now); ok {
+			counters[i] = e.value
+		}
+	}
+	return counters, nil
+}
+
+func (mcs *MemoryCounterStorage) IncrementCounter(ctx context.Context, key string, amount int64, ttl time.Duration) (int64, error) {
+	mcs.mu.Lock()
+	defer mcs.mu.Unlock()
+
+	now := mcs.now()
+	e, ok := mcs.get(key, now)
+	if !ok {
+		e = mcs.put(key, 0)
+	}
+
+	e.value += amount
+	if ttl > 0 {
+		e.expiresAt = now.Add(ttl)
+	}
+	return e.value, nil
+}
+
+func (mcs *MemoryCounterStorage) IncrementWithinLimit(ctx context.Context, previousKey, currentKey string, previousWeight float64, amount, limit int64, ttl time.Duration) (*ratelimit.CounterWindow, error) {
+	mcs.mu.Lock()
+	defer mcs.mu.Unlock()
+
+	now := mcs.now()
+	window := &ratelimit.CounterWindow{}
+	if e, ok := mcs.get(previousKey, now); ok {
+		window.Previous = e.value
+	}
+	current, ok := mcs.get(currentKey, now)
+	if ok {
+		window.Current = current.value
+	}
+
+	if float64(window.Previous)*previousWeight+float64(window.Current)+float64(amount) > float64(limit) {
+		return window, nil
+	}
+
+	if !ok {
+		current = mcs.put(currentKey, 0)
+	}
+	current.value += amount
+	if ttl > 0 {
+		current.expiresAt = now.Add(ttl)
+	}
+	window.Added = true
+	return window, nil
+}
@@ -0,0 +1,49 @@
+package memory
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/GeovaneCavalcante/rate-limit-api/pkg/ratelimit"
+	"github.com/GeovaneCavalcante/rate-limit-api/pkg/ratelimit/clocktest"
+	"github.com/GeovaneCavalcante/rate-limit-api/pkg/ratelimit/storagetest"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMemoryCounterStorage(t *testing.T) {
+	ctx := context.Background()
+	clock := clocktest.NewFakeClock(time.Unix(1700000000, 0))
+	s := NewMemoryCounterStorage(0, WithClock(clock))
+
+	counters, err := s.GetCounters(ctx, "a", "b")
+	assert.NoError(t, err)
+	assert.Equal(t, []int64{0, 0}, counters)
+
+	value, err := s.IncrementCounter(ctx, "b", 1, time.Minute)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), value)
+
+	value, _ = s.IncrementCounter(ctx, "b", 1, time.Minute)
+	assert.Equal(t, int64(2), value)
+
+	_, _ = s.IncrementCounter(ctx, "expired", 1, time.Second)
+	clock.Advance(time.Second)
+
+	counters, _ = s.GetCounters(ctx, "a", "b", "expired")
+	assert.Equal(t, []int64{0, 2, 0}, counters)
+}
+
+func TestMemoryCounterStorageConformance(t *testing.T) {
+	var clock *clocktest.FakeClock
+
+	storagetest.RunCounterStorageTests(t, storagetest.CounterBackend{
+		NewStorage: func(t *testing.T) ratelimit.CounterStorageInterface {
+			clock = clocktest.NewFakeClock(time.Unix(1700000000, 0))
+			return NewMemoryCounterStorage(0, WithClock(clock))
+		},
+		FastForward: func(t *testing.T, d time.Duration) {
+			clock.Advance(d)
+		},
+	})
+}
@@ -0,0 +1,311 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/GeovaneCavalcante/rate-limit-api/pkg/ratelimit"
+)
+
+// MemoryEventStorage keeps the events of each key sorted by score, mirroring
+// the sorted sets used by the Redis storage.
+type MemoryEventStorage struct {
+	*store[*eventSet]
+}
+
+func NewMemoryEventStorage(maxKeys int, opts ...Option) *MemoryEventStorage {
+	return &MemoryEventStorage{
+		store: newStore[*eventSet](maxKeys, opts),
+	}
+}
+
+func (mes *MemoryEventStorage) CountRange(ctx context.Context, key, min, max string) (int64, error) {
+	minBound, err := parseBound(min)
+	if err != nil {
+		return 0, err
+	}
+	maxBound, err := parseBound(max)
+	if err != nil {
+		return 0, err
+	}
+
+	mes.mu.Lock()
+	defer mes.mu.Unlock()
+
+	e, ok := mes.get(key, mes.now())
+	if !ok {
+		return 0, nil
+	}
+
+	var count int64
+	for _, event := range e.value.events {
+		if minBound.lowerThanOrEqual(event.Score) && maxBound.greaterThanOrEqual(event.Score) {
+			count++
+		}
+	}
+	return count, nil
+}
+
+func (mes *MemoryEventStorage) FindRangeWithScores(ctx context.Context, key string, start, stop int64) ([]*ratelimit.Event, error) {
+	mes.mu.Lock()
+	defer mes.mu.Unlock()
+
+	e, ok := mes.get(key, mes.now())
+	if !ok {
+		return nil, nil
+	}
+
+	size := int64(len(e.value.events))
+	if start < 0 {
+		start = max(0, size+start)
+	}
+	if stop < 0 {
+		stop = size + stop
+	}
+	stop = min(stop, size-1)
+
+	var events []*ratelimit.Event
+	for i := start; i <= stop; i++ {
+		event := e.value.events[i]
+		events = append(events, &ratelimit.Event{
+			ID:    fmt.Sprint(i - start),
+			Score: event.Score,
+			Value: event.Value,
+		})
+	}
+	return events, nil
+}
+
+func (mes *MemoryEventStorage) RemoveRangeByScore(ctx context.Context, key, min, max string) error {
+	minBound, err := parseBound(min)
+	if err != nil {
+		return err
+	}
+	maxBound, err := parseBound(max)
+	if err != nil {
+		return err
+	}
+
+	mes.mu.Lock()
+	defer mes.mu.Unlock()
+
+	e, ok := mes.get(key, mes.now())
+	if !ok {
+		return nil
+	}
+
+	e.value.removeFunc(func(event *ratelimit.Event) bool {
+		return minBound.lowerThanOrEqual(event.Score) && maxBound.greaterThanOrEqual(event.Score)
+	})
+	mes.removeEmpty(e)
+	return nil
+}
+
+func (mes *MemoryEventStorage) Add(ctx context.Context, key string, events ...*ratelimit.Event) ([]*ratelimit.Event, error) {
+	mes.mu.Lock()
+	defer mes.mu.Unlock()
+
+	e, ok := mes.get(key, mes.now())
+	if !ok {
+		e = mes.put(key, newEventSet())
+	}
+
+	for _, event := range events {
+		e.value.insert(event)
+	}
+	return events, nil
+}
+
+func (mes *MemoryEventStorage) SetEventTLL(ctx context.Context, key string, ttl time.Duration) error {
+	mes.mu.Lock()
+	defer mes.mu.Unlock()
+
+	e, ok := mes.get(key, mes.now())
+	if !ok {
+		return nil
+	}
+	if ttl <= 0 {
+		mes.remove(e)
+		return nil
+	}
+	e.expiresAt = mes.now().Add(ttl)
+	return nil
+}
+
+func (mes *MemoryEventStorage) AddWithinLimit(ctx context.Context, key string, events []*ratelimit.Event, windowStart float64, limit int64, ttl time.Duration) (*ratelimit.EventWindow, error) {
+	mes.mu.Lock()
+	defer mes.mu.Unlock()
+
+	now := mes.now()
+	e, ok := mes.get(key, now)
+	if !ok {
+		e = mes.put(key, newEventSet())
+	}
+	set := e.value
+
+	set.removeFirst(sort.Search(len(set.events), func(i int) bool { return set.events[i].Score > windowStart }))
+
+	var weight int64
+	for _, event := range events {
+		weight += ratelimit.EventWeight(event.Value)
+	}
+
+	window := &ratelimit.EventWindow{Count: set.weight}
+	if set.weight+weight <= limit {
+		for _, event := range events {
+			set.insert(event)
+		}
+		window.Count = set.weight
+		window.Added = true
+	}
+
+	if mes.removeEmpty(e) {
+		return window, nil
+	}
+
+	if !window.Added {
+		window.RetryScore = set.retryScore(window.Count + weight - limit)
+	}
+
+	if ttl > 0 {
+		e.expiresAt = now.Add(ttl)
+	}
+	window.OldestScore = set.events[0].Score
+	return window, nil
+}
+
+// removeEmpty removes an entry without events like Redis does with empty
+// sorted sets.
+func (mes *MemoryEventStorage) removeEmpty(e *entry[*eventSet]) bool {
+	if len(e.value.events) > 0 {
+		return false
+	}
+	mes.remove(e)
+	return true
+}
+
+// eventSet keeps the events ordered by score and then by value, with the
+// score of each value so an event can be replaced without a linear scan.
+type eventSet struct {
+	events []*ratelimit.Event
+	scores map[string]float64
+	weight int64
+}
+
+func newEventSet() *eventSet {
+	return &eventSet{scores: make(map[string]float64)}
+}
+
+// insert adds an event, replacing the score of an event with the same value.
+func (s *eventSet) insert(event *ratelimit.Event) {
+	if score, ok := s.scores[event.Value]; ok {
+		i := s.search(score, event.Value)
+		s.events = append(s.events[:i], s.events[i+1:]...)
+		s.weight -= ratelimit.EventWeight(event.Value)
+	}
+
+	i := s.search(event.Score, event.Value)
+	s.events = append(s.events, nil)
+	copy(s.events[i+1:], s.events[i:])
+	s.events[i] = &ratelimit.Event{ID: event.ID, Score: event.Score, Value: event.Value}
+	s.scores[event.Value] = event.Score
+	s.weight += ratelimit.EventWeight(event.Value)
+}
+
+// search returns the position of the event with the given score and value,
+// or where it would be inserted.
+func (s *eventSet) search(score float64, value string) int {
+	return sort.Search(len(s.events), func(i int) bool {
+		if s.events[i].Score != score {
+			return s.events[i].Score > score
+		}
+		return s.events[i].Value >= value
+	})
+}
+
+// removeFirst removes the n events with the lowest scores.
+func (s *eventSet) removeFirst(n int) {
+	for _, event := range s.events[:n] {
+		s.forget(event)
+	}
+	s.events = s.events[n:]
+}
+
+func (s *eventSet) removeFunc(remove func(*ratelimit.Event) bool) {
+	kept := s.events[:0]
+	for _, event := range s.events {
+		if remove(event) {
+			s.forget(event)
+			continue
+		}
+		kept = append(kept, event)
+	}
+	s.events = kept
+}
+
+func (s *eventSet) forget(event *ratelimit.Event) {
+	delete(s.scores, event.Value)
+	s.weight -= ratelimit.EventWeight(event.Value)
+}
+
+// retryScore returns the score of the event after which at least the given
+// weight has left the window, or of the newest event when there is not
+// enough weight stored.
+func (s *eventSet) retryScore(weight int64) float64 {
+	var released int64
+	for _, event := range s.events {
+		released += ratelimit.EventWeight(event.Value)
+		if released >= weight {
+			return event.Score
+		}
+	}
+	return s.events[len(s.events)-1].Score
+}
+
+type bound struct {
+	value     float64
+	exclusive bool
+}
+
+func (b bound) lowerThanOrEqual(score float64) bool {
+	if b.exclusive {
+		return b.value < score
+	}
+	return b.value <= score
+}
+
+func (b bound) greaterThanOrEqual(score float64) bool {
+	if b.exclusive {
+		return b.value > score
+	}
+	return b.value >= score
+}
+
+// parseBound parses a score bound with the Redis syntax, "(" makes the bound
+// exclusive, plus the "min" sentinel used by the ratelimit package.
+func parseBound(s string) (bound, error) {
+	switch s {
+	case "min", "-inf":
+		return bound{value: math.Inf(-1)}, nil
+	case "+inf", "inf":
+		return bound{value: math.Inf(1)}, nil
+	}
+
+	b := bound{}
+	if strings.HasPrefix(s, "(") {
+		b.exclusive = true
+		s = s[1:]
+	}
+
+	value, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return bound{}, fmt.Errorf("invalid score bound %q: %w", s, err)
+	}
+	b.value = value
+	return b, nil
+}
@@ -0,0 +1,24 @@
+package memory
+
+import (
+	"testing"
+	"time"
+
+	"github.com/GeovaneCavalcante/rate-limit-api/pkg/ratelimit"
+	"github.com/GeovaneCavalcante/rate-limit-api/pkg/ratelimit/clocktest"
+	"github.com/GeovaneCavalcante/rate-limit-api/pkg/ratelimit/storagetest"
+)
+
+func TestMemoryEventStorage(t *testing.T) {
+	var clock *clocktest.FakeClock
+
+	storagetest.RunEventStorageTests(t, storagetest.Backend{
+		NewStorage: func(t *testing.T) ratelimit.EventStorageInterface {
+			clock = clocktest.NewFakeClock(time.Unix(1700000000, 0))
+			return NewMemoryEventStorage(0, WithClock(clock))
+		},
+		FastForward: func(t *testing.T, d time.Duration) {
+			clock.Advance(d)
+		},
+	})
+}
@@ -0,0 +1,36 @@
+package memory
+
+import (
+	"context"
+	"time"
+)
+
+type MemoryTATStorage struct {
+	*store[int64]
+}
+
+func NewMemoryTATStorage(maxKeys int, opts ...Option) *MemoryTATStorage {
+	return &MemoryTATStorage{
+		store: newStore[int64](maxKeys, opts),
+	}
+}
+
+func (mts *MemoryTATStorage) TakeTAT(ctx context.Context, key string, now, increment, limit int64) (int64, bool, error) {
+	mts.mu.Lock()
+	defer mts.mu.Unlock()
+
+	storeNow := mts.now()
+	tat := now
+	if e, ok := mts.get(key, storeNow); ok {
+		tat = max(tat, e.value)
+	}
+
+	newTAT := tat + increment
+	if newTAT < tat || newTAT > limit {
+		return tat, false, nil
+	}
+
+	e := mts.put(key, newTAT)
+	e.expiresAt = storeNow.Add(time.Duration(newTAT - now))
+	return tat, true, nil
+}
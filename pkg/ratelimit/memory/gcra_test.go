@@ -0,0 +1,34 @@
+package memory
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/GeovaneCavalcante/rate-limit-api/pkg/ratelimit/clocktest"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMemoryTATStorage(t *testing.T) {
+	ctx := context.Background()
+	clock := clocktest.NewFakeClock(time.Unix(0, 100))
+	s := NewMemoryTATStorage(0, WithClock(clock))
+
+	tat, allowed, err := s.TakeTAT(ctx, "key", 100, 10, 115)
+	assert.NoError(t, err)
+	assert.True(t, allowed)
+	assert.Equal(t, int64(100), tat)
+
+	tat, allowed, _ = s.TakeTAT(ctx, "key", 100, 10, 115)
+	assert.False(t, allowed)
+	assert.Equal(t, int64(110), tat)
+
+	tat, allowed, _ = s.TakeTAT(ctx, "key", 105, 10, 120)
+	assert.True(t, allowed)
+	assert.Equal(t, int64(110), tat)
+
+	clock.Advance(20)
+	tat, allowed, _ = s.TakeTAT(ctx, "key", 120, 10, 135)
+	assert.True(t, allowed)
+	assert.Equal(t, int64(120), tat)
+}
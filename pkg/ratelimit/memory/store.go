@@ -0,0 +1,134 @@
+package memory
+
+import (
+	"container/list"
+	"context"
+	"sync"
+	"time"
+
+	"github.com/GeovaneCavalcante/rate-limit-api/pkg/ratelimit"
+)
+
+type settings struct {
+	clock ratelimit.Clock
+}
+
+// Option configures the optional settings of the memory storages.
+type Option func(*settings)
+
+// WithClock replaces the real clock used to expire the entries.
+func WithClock(c ratelimit.Clock) Option {
+	return func(s *settings) {
+		s.clock = c
+	}
+}
+
+type entry[V any] struct {
+	key       string
+	value     V
+	expiresAt time.Time
+	element   *list.Element
+}
+
+func (e *entry[V]) expired(now time.Time) bool {
+	return !e.expiresAt.IsZero() && !now.Before(e.expiresAt)
+}
+
+// store is the map shared by the memory storages. Expired entries are removed
+// when read and by RemoveExpired, and once maxKeys is reached the least
+// recently used entry is evicted to bound the memory. Callers of get, put and
+// remove must hold mu.
+type store[V any] struct {
+	mu      sync.Mutex
+	entries map[string]*entry[V]
+	lru     *list.List
+	maxKeys int
+	clock   ratelimit.Clock
+}
+
+func newStore[V any](maxKeys int, opts []Option) *store[V] {
+	cfg := settings{clock: ratelimit.RealClock()}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	s := &store[V]{
+		entries: make(map[string]*entry[V]),
+		lru:     list.New(),
+		maxKeys: maxKeys,
+		clock:   cfg.clock,
+	}
+	return s
+}
+
+func (s *store[V]) get(key string, now time.Time) (*entry[V], bool) {
+	e, ok := s.entries[key]
+	if !ok {
+		return nil, false
+	}
+	if e.expired(now) {
+		s.remove(e)
+		return nil, false
+	}
+	s.lru.MoveToFront(e.element)
+	return e, true
+}
+
+func (s *store[V]) put(key string, value V) *entry[V] {
+	if e, ok := s.entries[key]; ok {
+		e.value = value
+		e.expiresAt = time.Time{}
+		s.lru.MoveToFront(e.element)
+		return e
+	}
+
+	if s.maxKeys > 0 && len(s.entries) >= s.maxKeys {
+		s.evictOldest()
+	}
+
+	e := &entry[V]{key: key, value: value}
+	e.element = s.lru.PushFront(e)
+	s.entries[key] = e
+	return e
+}
+
+func (s *store[V]) remove(e *entry[V]) {
+	s.lru.Remove(e.element)
+	delete(s.entries, e.key)
+}
+
+func (s *store[V]) evictOldest() {
+	if oldest := s.lru.Back(); oldest != nil {
+		s.remove(oldest.Value.(*entry[V]))
+	}
+}
+
+func (s *store[V]) removeExpired(now time.Time) int {
+	removed := 0
+	for _, e := range s.entries {
+		if e.expired(now) {
+			s.remove(e)
+			removed++
+		}
+	}
+	return removed
+}
+
+// RemoveExpired deletes the expired keys right away, it is called periodically
+// by a ratelimit.Sweeper so the keys that are never read again do not
+// accumulate.
+func (s *store[V]) RemoveExpired(ctx context.Context) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.removeExpired(s.now()), nil
+}
+
+func (s *store[V]) now() time.Time {
+	return s.clock.Now()
+}
+
+func (s *store[V]) size() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.entries)
+}
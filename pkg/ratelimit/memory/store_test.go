@@ -0,0 +1,41 @@
+package memory
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/GeovaneCavalcante/rate-limit-api/pkg/ratelimit/clocktest"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStore(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("should evict the least recently used key when full", func(t *testing.T) {
+		s := NewMemoryCounterStorage(2)
+
+		_, _ = s.IncrementCounter(ctx, "a", 1, time.Minute)
+		_, _ = s.IncrementCounter(ctx, "b", 1, time.Minute)
+		_, _ = s.GetCounters(ctx, "a")
+		_, _ = s.IncrementCounter(ctx, "c", 1, time.Minute)
+
+		counters, _ := s.GetCounters(ctx, "a", "b", "c")
+		assert.Equal(t, []int64{1, 0, 1}, counters)
+		assert.Equal(t, 2, s.size())
+	})
+
+	t.Run("should remove the expired keys on demand", func(t *testing.T) {
+		clock := clocktest.NewFakeClock(time.Unix(1700000000, 0))
+		s := NewMemoryCounterStorage(0, WithClock(clock))
+
+		_, _ = s.IncrementCounter(ctx, "a", 1, time.Second)
+		_, _ = s.IncrementCounter(ctx, "b", 1, time.Minute)
+		clock.Advance(time.Second)
+
+		removed, err := s.RemoveExpired(ctx)
+		assert.NoError(t, err)
+		assert.Equal(t, 1, removed)
+		assert.Equal(t, 1, s.size())
+	})
+}
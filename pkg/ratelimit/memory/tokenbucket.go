@@ -0,0 +1,36 @@
+package memory
+
+import (
+	"context"
+	"time"
+
+	"github.com/GeovaneCavalcante/rate-limit-api/pkg/ratelimit"
+)
+
+type MemoryTokenBucketStorage struct {
+	*store[*ratelimit.TokenBucket]
+}
+
+func NewMemoryTokenBucketStorage(maxKeys int, opts ...Option) *MemoryTokenBucketStorage {
+	return &MemoryTokenBucketStorage{
+		store: newStore[*ratelimit.TokenBucket](maxKeys, opts),
+	}
+}
+
+func (mts *MemoryTokenBucketStorage) TakeToken(ctx context.Context, key string, capacity, cost int64, refillRate, now float64, ttl time.Duration) (float64, bool, error) {
+	mts.mu.Lock()
+	defer mts.mu.Unlock()
+
+	current := mts.now()
+	e, ok := mts.get(key, current)
+	if !ok {
+		e = mts.put(key, ratelimit.NewTokenBucket(capacity, now))
+	}
+
+	allowed := e.value.Take(capacity, cost, refillRate, now)
+	if ttl > 0 {
+		e.expiresAt = current.Add(ttl)
+	}
+
+	return e.value.Tokens, allowed, nil
+}
@@ -0,0 +1,32 @@
+package memory
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMemoryTokenBucketStorage(t *testing.T) {
+	ctx := context.Background()
+	s := NewMemoryTokenBucketStorage(0)
+
+	tokens, allowed, err := s.TakeToken(ctx, "key", 2, 1, 1, 100, time.Minute)
+	assert.NoError(t, err)
+	assert.True(t, allowed)
+	assert.Equal(t, float64(1), tokens)
+
+	_, allowed, _ = s.TakeToken(ctx, "key", 2, 1, 1, 100, time.Minute)
+	assert.True(t, allowed)
+
+	tokens, allowed, _ = s.TakeToken(ctx, "key", 2, 1, 1, 100.5, time.Minute)
+	assert.False(t, allowed)
+	assert.Equal(t, 0.5, tokens)
+
+	_, allowed, _ = s.TakeToken(ctx, "other", 2, 1, 1, 100.5, time.Minute)
+	assert.True(t, allowed)
+
+	_, allowed, _ = s.TakeToken(ctx, "key", 2, 1, 1, 101, time.Minute)
+	assert.True(t, allowed)
+}
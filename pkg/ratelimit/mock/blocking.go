@@ -0,0 +1,70 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: pkg/ratelimit/blocking.go
+//
+// Generated by this command:
+//
+//	mockgen -source=pkg/ratelimit/blocking.go -destination=pkg/ratelimit/mock/blocking.go
+//
+
+// Package mock_ratelimit is a generated GoMock package.
+package mock_ratelimit
+
+import (
+	context "context"
+	reflect "reflect"
+	time "time"
+
+	gomock "go.uber.org/mock/gomock"
+)
+
+// MockBlockStorageInterface is a mock of BlockStorageInterface interface.
+type MockBlockStorageInterface struct {
+	ctrl     *gomock.Controller
+	recorder *MockBlockStorageInterfaceMockRecorder
+}
+
+// MockBlockStorageInterfaceMockRecorder is the mock recorder for MockBlockStorageInterface.
+type MockBlockStorageInterfaceMockRecorder struct {
+	mock *MockBlockStorageInterface
+}
+
+// NewMockBlockStorageInterface creates a new mock instance.
+func NewMockBlockStorageInterface(ctrl *gomock.Controller) *MockBlockStorageInterface {
+	mock := &MockBlockStorageInterface{ctrl: ctrl}
+	mock.recorder = &MockBlockStorageInterfaceMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockBlockStorageInterface) EXPECT() *MockBlockStorageInterfaceMockRecorder {
+	return m.recorder
+}
+
+// Block mocks base method.
+func (m *MockBlockStorageInterface) Block(ctx context.Context, key string, until time.Time) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Block", ctx, key, until)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Block indicates an expected call of Block.
+func (mr *MockBlockStorageInterfaceMockRecorder) Block(ctx, key, until any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Block", reflect.TypeOf((*MockBlockStorageInterface)(nil).Block), ctx, key, until)
+}
+
+// BlockedUntil mocks base method.
+func (m *MockBlockStorageInterface) BlockedUntil(ctx context.Context, key string) (time.Time, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "BlockedUntil", ctx, key)
+	ret0, _ := ret[0].(time.Time)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// BlockedUntil indicates an expected call of BlockedUntil.
+func (mr *MockBlockStorageInterfaceMockRecorder) BlockedUntil(ctx, key any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "BlockedUntil", reflect.TypeOf((*MockBlockStorageInterface)(nil).BlockedUntil), ctx, key)
+}
@@ -118,3 +118,119 @@ func (mr *MockEventStorageInterfaceMockRecorder) SetEventTLL(ctx, key, ttl any)
 	mr.mock.ctrl.T.Helper()
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetEventTLL", reflect.TypeOf((*MockEventStorageInterface)(nil).SetEventTLL), ctx, key, ttl)
 }
+
+// MockAtomicEventStorage is a mock of AtomicEventStorage interface.
+type MockAtomicEventStorage struct {
+	ctrl     *gomock.Controller
+	recorder *MockAtomicEventStorageMockRecorder
+}
+
+// MockAtomicEventStorageMockRecorder is the mock recorder for MockAtomicEventStorage.
+type MockAtomicEventStorageMockRecorder struct {
+	mock *MockAtomicEventStorage
+}
+
+// NewMockAtomicEventStorage creates a new mock instance.
+func NewMockAtomicEventStorage(ctrl *gomock.Controller) *MockAtomicEventStorage {
+	mock := &MockAtomicEventStorage{ctrl: ctrl}
+	mock.recorder = &MockAtomicEventStorageMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockAtomicEventStorage) EXPECT() *MockAtomicEventStorageMockRecorder {
+	return m.recorder
+}
+
+// Add mocks base method.
+func (m *MockAtomicEventStorage) Add(ctx context.Context, key string, events ...*ratelimit.Event) ([]*ratelimit.Event, error) {
+	m.ctrl.T.Helper()
+	varargs := []any{ctx, key}
+	for _, a := range events {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "Add", varargs...)
+	ret0, _ := ret[0].([]*ratelimit.Event)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Add indicates an expected call of Add.
+func (mr *MockAtomicEventStorageMockRecorder) Add(ctx, key any, events ...any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	varargs := append([]any{ctx, key}, events...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Add", reflect.TypeOf((*MockAtomicEventStorage)(nil).Add), varargs...)
+}
+
+// AddWithinLimit mocks base method.
+func (m *MockAtomicEventStorage) AddWithinLimit(ctx context.Context, key string, events []*ratelimit.Event, windowStart float64, limit int64, ttl time.Duration) (*ratelimit.EventWindow, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "AddWithinLimit", ctx, key, events, windowStart, limit, ttl)
+	ret0, _ := ret[0].(*ratelimit.EventWindow)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// AddWithinLimit indicates an expected call of AddWithinLimit.
+func (mr *MockAtomicEventStorageMockRecorder) AddWithinLimit(ctx, key, events, windowStart, limit, ttl any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AddWithinLimit", reflect.TypeOf((*MockAtomicEventStorage)(nil).AddWithinLimit), ctx, key, events, windowStart, limit, ttl)
+}
+
+// CountRange mocks base method.
+func (m *MockAtomicEventStorage) CountRange(ctx context.Context, key, min, max string) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CountRange", ctx, key, min, max)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CountRange indicates an expected call of CountRange.
+func (mr *MockAtomicEventStorageMockRecorder) CountRange(ctx, key, min, max any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CountRange", reflect.TypeOf((*MockAtomicEventStorage)(nil).CountRange), ctx, key, min, max)
+}
+
+// FindRangeWithScores mocks base method.
+func (m *MockAtomicEventStorage) FindRangeWithScores(ctx context.Context, key string, start, stop int64) ([]*ratelimit.Event, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FindRangeWithScores", ctx, key, start, stop)
+	ret0, _ := ret[0].([]*ratelimit.Event)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// FindRangeWithScores indicates an expected call of FindRangeWithScores.
+func (mr *MockAtomicEventStorageMockRecorder) FindRangeWithScores(ctx, key, start, stop any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FindRangeWithScores", reflect.TypeOf((*MockAtomicEventStorage)(nil).FindRangeWithScores), ctx, key, start, stop)
+}
+
+// RemoveRangeByScore mocks base method.
+func (m *MockAtomicEventStorage) RemoveRangeByScore(ctx context.Context, key, min, max string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RemoveRangeByScore", ctx, key, min, max)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// RemoveRangeByScore indicates an expected call of RemoveRangeByScore.
+func (mr *MockAtomicEventStorageMockRecorder) RemoveRangeByScore(ctx, key, min, max any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RemoveRangeByScore", reflect.TypeOf((*MockAtomicEventStorage)(nil).RemoveRangeByScore), ctx, key, min, max)
+}
+
+// SetEventTLL mocks base method.
+func (m *MockAtomicEventStorage) SetEventTLL(ctx context.Context, key string, ttl time.Duration) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SetEventTLL", ctx, key, ttl)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SetEventTLL indicates an expected call of SetEventTLL.
+func (mr *MockAtomicEventStorageMockRecorder) SetEventTLL(ctx, key, ttl any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetEventTLL", reflect.TypeOf((*MockAtomicEventStorage)(nil).SetEventTLL), ctx, key, ttl)
+}
@@ -0,0 +1,56 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: pkg/ratelimit/gcra.go
+//
+// Generated by this command:
+//
+//	mockgen -source=pkg/ratelimit/gcra.go -destination=pkg/ratelimit/mock/gcra.go
+//
+
+// Package mock_ratelimit is a generated GoMock package.
+package mock_ratelimit
+
+import (
+	context "context"
+	reflect "reflect"
+
+	gomock "go.uber.org/mock/gomock"
+)
+
+// MockTATStorageInterface is a mock of TATStorageInterface interface.
+type MockTATStorageInterface struct {
+	ctrl     *gomock.Controller
+	recorder *MockTATStorageInterfaceMockRecorder
+}
+
+// MockTATStorageInterfaceMockRecorder is the mock recorder for MockTATStorageInterface.
+type MockTATStorageInterfaceMockRecorder struct {
+	mock *MockTATStorageInterface
+}
+
+// NewMockTATStorageInterface creates a new mock instance.
+func NewMockTATStorageInterface(ctrl *gomock.Controller) *MockTATStorageInterface {
+	mock := &MockTATStorageInterface{ctrl: ctrl}
+	mock.recorder = &MockTATStorageInterfaceMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockTATStorageInterface) EXPECT() *MockTATStorageInterfaceMockRecorder {
+	return m.recorder
+}
+
+// TakeTAT mocks base method.
+func (m *MockTATStorageInterface) TakeTAT(ctx context.Context, key string, now, increment, limit int64) (int64, bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "TakeTAT", ctx, key, now, increment, limit)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(bool)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
+}
+
+// TakeTAT indicates an expected call of TakeTAT.
+func (mr *MockTATStorageInterfaceMockRecorder) TakeTAT(ctx, key, now, increment, limit any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "TakeTAT", reflect.TypeOf((*MockTATStorageInterface)(nil).TakeTAT), ctx, key, now, increment, limit)
+}
@@ -40,33 +40,19 @@ func (m *MockRateLimiterInterface) EXPECT() *MockRateLimiterInterfaceMockRecorde
 	return m.recorder
 }
 
-// AddEvent mocks base method.
-func (m *MockRateLimiterInterface) AddEvent(ctx context.Context, key string, timestamp int64) error {
+// Decide mocks base method.
+func (m *MockRateLimiterInterface) Decide(ctx context.Context, key string, opt *ratelimit.Options) (*ratelimit.Decision, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "AddEvent", ctx, key, timestamp)
-	ret0, _ := ret[0].(error)
-	return ret0
-}
-
-// AddEvent indicates an expected call of AddEvent.
-func (mr *MockRateLimiterInterfaceMockRecorder) AddEvent(ctx, key, timestamp any) *gomock.Call {
-	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AddEvent", reflect.TypeOf((*MockRateLimiterInterface)(nil).AddEvent), ctx, key, timestamp)
-}
-
-// CountEventsBeforeCurrent mocks base method.
-func (m *MockRateLimiterInterface) CountEventsBeforeCurrent(ctx context.Context, key string, currentTimestamp int64) (int64, error) {
-	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "CountEventsBeforeCurrent", ctx, key, currentTimestamp)
-	ret0, _ := ret[0].(int64)
+	ret := m.ctrl.Call(m, "Decide", ctx, key, opt)
+	ret0, _ := ret[0].(*ratelimit.Decision)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
-// CountEventsBeforeCurrent indicates an expected call of CountEventsBeforeCurrent.
-func (mr *MockRateLimiterInterfaceMockRecorder) CountEventsBeforeCurrent(ctx, key, currentTimestamp any) *gomock.Call {
+// Decide indicates an expected call of Decide.
+func (mr *MockRateLimiterInterfaceMockRecorder) Decide(ctx, key, opt any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CountEventsBeforeCurrent", reflect.TypeOf((*MockRateLimiterInterface)(nil).CountEventsBeforeCurrent), ctx, key, currentTimestamp)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Decide", reflect.TypeOf((*MockRateLimiterInterface)(nil).Decide), ctx, key, opt)
 }
 
 // Limiter mocks base method.
@@ -83,17 +69,3 @@ func (mr *MockRateLimiterInterfaceMockRecorder) Limiter(ctx, key, opt any) *gomo
 	mr.mock.ctrl.T.Helper()
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Limiter", reflect.TypeOf((*MockRateLimiterInterface)(nil).Limiter), ctx, key, opt)
 }
-
-// RemoveExpiredEvents mocks base method.
-func (m *MockRateLimiterInterface) RemoveExpiredEvents(ctx context.Context, key string, recentTimestamp, intervalSecund float64) error {
-	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "RemoveExpiredEvents", ctx, key, recentTimestamp, intervalSecund)
-	ret0, _ := ret[0].(error)
-	return ret0
-}
-
-// RemoveExpiredEvents indicates an expected call of RemoveExpiredEvents.
-func (mr *MockRateLimiterInterfaceMockRecorder) RemoveExpiredEvents(ctx, key, recentTimestamp, intervalSecund any) *gomock.Call {
-	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RemoveExpiredEvents", reflect.TypeOf((*MockRateLimiterInterface)(nil).RemoveExpiredEvents), ctx, key, recentTimestamp, intervalSecund)
-}
@@ -0,0 +1,76 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: pkg/ratelimit/slidingwindow.go
+//
+// Generated by this command:
+//
+//	mockgen -source=pkg/ratelimit/slidingwindow.go -destination=pkg/ratelimit/mock/slidingwindow.go
+//
+
+// Package mock_ratelimit is a generated GoMock package.
+package mock_ratelimit
+
+import (
+	context "context"
+	reflect "reflect"
+	time "time"
+
+	gomock "go.uber.org/mock/gomock"
+)
+
+// MockCounterStorageInterface is a mock of CounterStorageInterface interface.
+type MockCounterStorageInterface struct {
+	ctrl     *gomock.Controller
+	recorder *MockCounterStorageInterfaceMockRecorder
+}
+
+// MockCounterStorageInterfaceMockRecorder is the mock recorder for MockCounterStorageInterface.
+type MockCounterStorageInterfaceMockRecorder struct {
+	mock *MockCounterStorageInterface
+}
+
+// NewMockCounterStorageInterface creates a new mock instance.
+func NewMockCounterStorageInterface(ctrl *gomock.Controller) *MockCounterStorageInterface {
+	mock := &MockCounterStorageInterface{ctrl: ctrl}
+	mock.recorder = &MockCounterStorageInterfaceMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockCounterStorageInterface) EXPECT() *MockCounterStorageInterfaceMockRecorder {
+	return m.recorder
+}
+
+// GetCounters mocks base method.
+func (m *MockCounterStorageInterface) GetCounters(ctx context.Context, keys ...string) ([]int64, error) {
+	m.ctrl.T.Helper()
+	varargs := []any{ctx}
+	for _, a := range keys {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "GetCounters", varargs...)
+	ret0, _ := ret[0].([]int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetCounters indicates an expected call of GetCounters.
+func (mr *MockCounterStorageInterfaceMockRecorder) GetCounters(ctx any, keys ...any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	varargs := append([]any{ctx}, keys...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetCounters", reflect.TypeOf((*MockCounterStorageInterface)(nil).GetCounters), varargs...)
+}
+
+// IncrementCounter mocks base method.
+func (m *MockCounterStorageInterface) IncrementCounter(ctx context.Context, key string, amount int64, ttl time.Duration) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "IncrementCounter", ctx, key, amount, ttl)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// IncrementCounter indicates an expected call of IncrementCounter.
+func (mr *MockCounterStorageInterfaceMockRecorder) IncrementCounter(ctx, key, amount, ttl any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "IncrementCounter", reflect.TypeOf((*MockCounterStorageInterface)(nil).IncrementCounter), ctx, key, amount, ttl)
+}
@@ -0,0 +1,57 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: pkg/ratelimit/tokenbucket.go
+//
+// Generated by this command:
+//
+//	mockgen -source=pkg/ratelimit/tokenbucket.go -destination=pkg/ratelimit/mock/tokenbucket.go
+//
+
+// Package mock_ratelimit is a generated GoMock package.
+package mock_ratelimit
+
+import (
+	context "context"
+	reflect "reflect"
+	time "time"
+
+	gomock "go.uber.org/mock/gomock"
+)
+
+// MockTokenBucketStorageInterface is a mock of TokenBucketStorageInterface interface.
+type MockTokenBucketStorageInterface struct {
+	ctrl     *gomock.Controller
+	recorder *MockTokenBucketStorageInterfaceMockRecorder
+}
+
+// MockTokenBucketStorageInterfaceMockRecorder is the mock recorder for MockTokenBucketStorageInterface.
+type MockTokenBucketStorageInterfaceMockRecorder struct {
+	mock *MockTokenBucketStorageInterface
+}
+
+// NewMockTokenBucketStorageInterface creates a new mock instance.
+func NewMockTokenBucketStorageInterface(ctrl *gomock.Controller) *MockTokenBucketStorageInterface {
+	mock := &MockTokenBucketStorageInterface{ctrl: ctrl}
+	mock.recorder = &MockTokenBucketStorageInterfaceMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockTokenBucketStorageInterface) EXPECT() *MockTokenBucketStorageInterfaceMockRecorder {
+	return m.recorder
+}
+
+// TakeToken mocks base method.
+func (m *MockTokenBucketStorageInterface) TakeToken(ctx context.Context, key string, capacity, cost int64, refillRate, now float64, ttl time.Duration) (float64, bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "TakeToken", ctx, key, capacity, cost, refillRate, now, ttl)
+	ret0, _ := ret[0].(float64)
+	ret1, _ := ret[1].(bool)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
+}
+
+// TakeToken indicates an expected call of TakeToken.
+func (mr *MockTokenBucketStorageInterfaceMockRecorder) TakeToken(ctx, key, capacity, cost, refillRate, now, ttl any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "TakeToken", reflect.TypeOf((*MockTokenBucketStorageInterface)(nil).TakeToken), ctx, key, capacity, cost, refillRate, now, ttl)
+}
@@ -0,0 +1,92 @@
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+)
+
+// Limit is one of the windows enforced by the MultiLimiter.
+type Limit struct {
+	MaxInInterval int64
+	Interval      time.Duration
+	Burst         int64
+}
+
+// MultiLimiter enforces several limits on the same key, such as 10 requests
+// per second, 1000 per minute and 50000 per day. The limits are checked from
+// the shortest window to the longest one and the first rejection stops the
+// check, so a rejected request is not counted in the longer windows. Without
+// limits it only delegates to the wrapped limiter.
+type MultiLimiter struct {
+	RateLimiter RateLimiterInterface
+	Options
+}
+
+func NewMultiLimiter(rl RateLimiterInterface, limits []Limit) (*MultiLimiter, error) {
+	if err := validateLimits(limits); err != nil {
+		return nil, err
+	}
+
+	return &MultiLimiter{
+		RateLimiter: rl,
+		Options:     Options{Limits: limits},
+	}, nil
+}
+
+func (ml *MultiLimiter) Limiter(ctx context.Context, key string, opt *Options) (bool, error) {
+	return limited(ml.Decide(ctx, key, opt))
+}
+
+func (ml *MultiLimiter) Decide(ctx context.Context, key string, opt *Options) (*Decision, error) {
+	limits := ml.Limits
+	if opt != nil && len(opt.Limits) > 0 {
+		limits = opt.Limits
+	}
+
+	if len(limits) == 0 {
+		return ml.RateLimiter.Decide(ctx, key, opt)
+	}
+	if err := validateLimits(limits); err != nil {
+		return nil, err
+	}
+
+	sorted := make([]Limit, len(limits))
+	copy(sorted, limits)
+	sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].Interval < sorted[j].Interval })
+
+	decisions := make([]*Decision, 0, len(sorted))
+	for _, limit := range sorted {
+		limitOpt := Options{}
+		if opt != nil {
+			limitOpt = *opt
+		}
+		limitOpt.MaxInInterval = limit.MaxInInterval
+		limitOpt.Interval = limit.Interval
+		limitOpt.Burst = limit.Burst
+		limitOpt.Limits = nil
+
+		decision, err := ml.RateLimiter.Decide(ctx, fmt.Sprintf("%s:%s", key, limit.Interval), &limitOpt)
+		if err != nil {
+			return nil, fmt.Errorf("error when checking the limit of %d requests in %s: %w", limit.MaxInInterval, limit.Interval, err)
+		}
+		decision.Key = key
+		decisions = append(decisions, decision)
+
+		if !decision.Allowed {
+			break
+		}
+	}
+
+	return MostRestrictive(decisions...), nil
+}
+
+func validateLimits(limits []Limit) error {
+	for _, limit := range limits {
+		if limit.MaxInInterval <= 0 || limit.Interval <= 0 {
+			return fmt.Errorf("invalid limit: %d requests in %s", limit.MaxInInterval, limit.Interval)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,137 @@
+package ratelimit_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/GeovaneCavalcante/rate-limit-api/pkg/ratelimit"
+	"github.com/GeovaneCavalcante/rate-limit-api/pkg/ratelimit/clocktest"
+	"github.com/GeovaneCavalcante/rate-limit-api/pkg/ratelimit/memory"
+	mock_storage "github.com/GeovaneCavalcante/rate-limit-api/pkg/ratelimit/mock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+	"go.uber.org/mock/gomock"
+)
+
+type MultiLimiterTestSuite struct {
+	suite.Suite
+	RateLimiterMock *mock_storage.MockRateLimiterInterface
+}
+
+func (suite *MultiLimiterTestSuite) SetupTest() {
+	ctrl := gomock.NewController(suite.T())
+	suite.RateLimiterMock = mock_storage.NewMockRateLimiterInterface(ctrl)
+}
+
+func (suite *MultiLimiterTestSuite) TestDecide() {
+	suite.Run("should only delegate when there are no limits", func() {
+		opt := &ratelimit.Options{MaxInInterval: 5}
+		suite.RateLimiterMock.EXPECT().Decide(gomock.Any(), "key", opt).Return(&ratelimit.Decision{Allowed: true}, nil)
+
+		ml, err := ratelimit.NewMultiLimiter(suite.RateLimiterMock, nil)
+		if err != nil {
+			suite.FailNow(err.Error())
+		}
+
+		value, err := ml.Limiter(context.Background(), "key", opt)
+		assert.NoError(suite.T(), err)
+		assert.False(suite.T(), value)
+	})
+
+	suite.Run("should check the shortest window first and stop at the first rejection", func() {
+		suite.RateLimiterMock.EXPECT().Decide(gomock.Any(), "key:1s", &ratelimit.Options{NameSpace: "token", MaxInInterval: 10, Interval: time.Second}).
+			Return(&ratelimit.Decision{Allowed: false, Limit: 10, RetryAfter: 300 * time.Millisecond, Key: "key:1s"}, nil)
+
+		ml, err := ratelimit.NewMultiLimiter(suite.RateLimiterMock, nil)
+		if err != nil {
+			suite.FailNow(err.Error())
+		}
+
+		decision, err := ml.Decide(context.Background(), "key", &ratelimit.Options{
+			NameSpace: "token",
+			Limits: []ratelimit.Limit{
+				{MaxInInterval: 50000, Interval: 24 * time.Hour},
+				{MaxInInterval: 10, Interval: time.Second},
+			},
+		})
+		assert.NoError(suite.T(), err)
+		assert.False(suite.T(), decision.Allowed)
+		assert.Equal(suite.T(), int64(10), decision.Limit)
+		assert.Equal(suite.T(), "key", decision.Key)
+	})
+
+	suite.Run("should return an error when a limit fails", func() {
+		suite.RateLimiterMock.EXPECT().Decide(gomock.Any(), "key:1m0s", gomock.Any()).Return(nil, errors.New("error"))
+
+		ml, err := ratelimit.NewMultiLimiter(suite.RateLimiterMock, []ratelimit.Limit{{MaxInInterval: 1000, Interval: time.Minute}})
+		if err != nil {
+			suite.FailNow(err.Error())
+		}
+
+		value, err := ml.Limiter(context.Background(), "key", nil)
+		assert.Equal(suite.T(), "error when checking the limit of 1000 requests in 1m0s: error", err.Error())
+		assert.False(suite.T(), value)
+	})
+
+	suite.Run("should return an error when a limit is invalid", func() {
+		_, err := ratelimit.NewMultiLimiter(suite.RateLimiterMock, []ratelimit.Limit{{MaxInInterval: 10}})
+		assert.Equal(suite.T(), "invalid limit: 10 requests in 0s", err.Error())
+	})
+}
+
+func (suite *MultiLimiterTestSuite) TestWindows() {
+	clock := clocktest.NewFakeClock(time.Unix(1700000000, 0))
+	es := memory.NewMemoryEventStorage(0, memory.WithClock(clock))
+	rl, err := ratelimit.New(es, "test", 0, 0, ratelimit.WithClock(clock))
+	if err != nil {
+		suite.FailNow(err.Error())
+	}
+
+	ml, err := ratelimit.NewMultiLimiter(rl, []ratelimit.Limit{
+		{MaxInInterval: 5, Interval: time.Minute},
+		{MaxInInterval: 2, Interval: time.Second},
+	})
+	if err != nil {
+		suite.FailNow(err.Error())
+	}
+
+	decide := func() *ratelimit.Decision {
+		decision, err := ml.Decide(context.Background(), "key", nil)
+		if err != nil {
+			suite.FailNow(err.Error())
+		}
+		return decision
+	}
+
+	assert.True(suite.T(), decide().Allowed)
+	decision := decide()
+	assert.True(suite.T(), decision.Allowed)
+	assert.Equal(suite.T(), int64(0), decision.Remaining)
+	assert.Equal(suite.T(), time.Second, decision.Window)
+
+	decision = decide()
+	assert.False(suite.T(), decision.Allowed)
+	assert.Equal(suite.T(), time.Second, decision.Window)
+
+	clock.Advance(time.Second)
+	assert.True(suite.T(), decide().Allowed)
+	assert.True(suite.T(), decide().Allowed)
+
+	clock.Advance(time.Second)
+	decision = decide()
+	assert.True(suite.T(), decision.Allowed)
+	assert.Equal(suite.T(), int64(0), decision.Remaining)
+	assert.Equal(suite.T(), time.Minute, decision.Window)
+
+	clock.Advance(time.Second)
+	decision = decide()
+	assert.False(suite.T(), decision.Allowed)
+	assert.Equal(suite.T(), time.Minute, decision.Window)
+	assert.Equal(suite.T(), 57*time.Second, decision.RetryAfter)
+}
+
+func TestMultiLimiterSuite(t *testing.T) {
+	suite.Run(t, new(MultiLimiterTestSuite))
+}
@@ -11,69 +11,100 @@ import (
 
 const minScore = "min"
 
+// MaxCost is the largest cost of a request. The entry points reject larger
+// costs and the limiters deny them, as they deny any cost above the limit,
+// before touching the storage.
+const MaxCost = 10000
+
+const (
+	AlgorithmSlidingLog    = "sliding_log"
+	AlgorithmTokenBucket   = "token_bucket"
+	AlgorithmSlidingWindow = "sliding_window"
+	AlgorithmGCRA          = "gcra"
+)
+
 type RateLimiterInterface interface {
-	CountEventsBeforeCurrent(ctx context.Context, key string, currentTimestamp int64) (int64, error)
-	RemoveExpiredEvents(ctx context.Context, key string, recentTimestamp, intervalSecund float64) error
-	AddEvent(ctx context.Context, key string, timestamp int64) error
 	Limiter(ctx context.Context, key string, opt *Options) (bool, error)
+	Decide(ctx context.Context, key string, opt *Options) (*Decision, error)
 }
 
 type Options struct {
-	NameSpace      string
-	MaxInInterval  int64
-	IntervalSecund int64
+	NameSpace     string
+	MaxInInterval int64
+	Interval      time.Duration
+	// Burst is the token bucket capacity, it defaults to MaxInInterval. For
+	// the GCRA it is the number of requests tolerated ahead of the pace.
+	Burst int64
+	// BlockDuration is how long a key stays blocked once it exceeds the
+	// limit, it is only used by the BlockingLimiter.
+	BlockDuration time.Duration
+	// Limits replaces MaxInInterval and Interval by several windows enforced
+	// together, it is only used by the MultiLimiter.
+	Limits []Limit
+	// Cost is the number of units the request consumes, it defaults to 1
+	// and may not exceed MaxCost.
+	Cost int64
 }
+
+// RateLimiter is the sliding log limiter, it stores one event per request
+// scored by its Unix timestamp in milliseconds.
 type RateLimiter struct {
 	EventStorage EventStorageInterface
+	Clock        Clock
 	Options
 }
 
-func New(es EventStorageInterface, ns string, max int64, inter time.Duration) (*RateLimiter, error) {
+func New(es EventStorageInterface, ns string, max int64, inter time.Duration, opts ...Option) (*RateLimiter, error) {
+	s := newSettings(opts)
 
 	return &RateLimiter{
 		EventStorage: es,
-		Options:      Options{NameSpace: ns, MaxInInterval: max, IntervalSecund: int64(inter.Seconds())},
+		Clock:        s.clock,
+		Options:      Options{NameSpace: ns, MaxInInterval: max, Interval: inter},
 	}, nil
 }
 
+// CountEventsBeforeCurrent returns the weight of the events scored up to
+// currentTimestamp.
 func (rl *RateLimiter) CountEventsBeforeCurrent(ctx context.Context, key string, currentTimestamp int64) (int64, error) {
-	count, err := rl.EventStorage.CountRange(ctx, key, minScore, strconv.FormatInt(currentTimestamp+1, 10))
-	if err != nil {
-		return 0, fmt.Errorf("error when counting the number of events: %w", err)
-	}
-	return count, nil
+	_, count, err := rl.eventsBeforeCurrent(ctx, key, currentTimestamp)
+	return count, err
 }
 
-func (rl *RateLimiter) RemoveExpiredEvents(ctx context.Context, key string, recentTimestamp, intervalSecund float64) error {
-	oldestEvent, err := rl.EventStorage.FindRangeWithScores(ctx, key, 0, 0)
+// eventsBeforeCurrent returns the events scored up to currentTimestamp, oldest
+// first, and the sum of their weights.
+func (rl *RateLimiter) eventsBeforeCurrent(ctx context.Context, key string, currentTimestamp int64) ([]*Event, int64, error) {
+	events, err := rl.EventStorage.FindRangeWithScores(ctx, key, 0, -1)
 	if err != nil {
-		return fmt.Errorf("error when finding the oldest event: %w", err)
+		return nil, 0, fmt.Errorf("error when counting the number of events: %w", err)
 	}
-	if len(oldestEvent) == 0 {
-		return nil
+
+	var count int64
+	current := events[:0]
+	for _, event := range events {
+		if event.Score <= float64(currentTimestamp) {
+			current = append(current, event)
+			count += EventWeight(event.Value)
+		}
 	}
+	return current, count, nil
+}
 
-	oldestTimestamp := oldestEvent[0].Score
+// RemoveExpiredEvents trims the events that left the window, that is every
+// event scored at or before recentTimestamp minus the interval.
+func (rl *RateLimiter) RemoveExpiredEvents(ctx context.Context, key string, recentTimestamp int64, interval time.Duration) error {
+	windowStart := recentTimestamp - interval.Milliseconds()
 
-	if recentTimestamp-oldestTimestamp > intervalSecund {
-		err := rl.EventStorage.RemoveRangeByScore(ctx, key, minScore, strconv.FormatFloat(recentTimestamp, 'f', -1, 64))
-		if err != nil {
-			return fmt.Errorf("error when removing expired events remove range by score: %w", err)
-		}
+	err := rl.EventStorage.RemoveRangeByScore(ctx, key, minScore, strconv.FormatInt(windowStart, 10))
+	if err != nil {
+		return fmt.Errorf("error when removing expired events remove range by score: %w", err)
 	}
 
 	return nil
 }
 
-func (rl *RateLimiter) AddEvent(ctx context.Context, key string, timestamp int64) error {
-	id := uuid.New().String()
-	value := fmt.Sprintf("event:%s:%d", id, timestamp)
-	score := float64(timestamp)
-
-	_, err := rl.EventStorage.Add(ctx, key, &Event{
-		Score: score,
-		Value: value,
-	})
+func (rl *RateLimiter) AddEvent(ctx context.Context, key string, timestamp, cost int64) error {
+	_, err := rl.EventStorage.Add(ctx, key, newEvent(timestamp, cost))
 
 	if err != nil {
 		return err
@@ -83,36 +114,137 @@ func (rl *RateLimiter) AddEvent(ctx context.Context, key string, timestamp int64
 }
 
 func (rl *RateLimiter) Limiter(ctx context.Context, key string, opt *Options) (bool, error) {
+	return limited(rl.Decide(ctx, key, opt))
+}
+
+func (rl *RateLimiter) Decide(ctx context.Context, key string, opt *Options) (*Decision, error) {
 
-	timestamp := time.Now().Unix()
+	timestamp := now(rl.Clock).UnixMilli()
 
 	nameSpace := chooseString(rl.NameSpace, opt, func(o *Options) string { return o.NameSpace })
 	maxInInterval := chooseInt64(rl.MaxInInterval, opt, func(o *Options) int64 { return o.MaxInInterval })
-	IntervalSecund := chooseInt64(rl.IntervalSecund, opt, func(o *Options) int64 { return o.IntervalSecund })
+	interval := chooseDuration(rl.Interval, opt, func(o *Options) time.Duration { return o.Interval })
+	cost := requestCost(opt)
 
 	bucketName := fmt.Sprintf("%s:%s", nameSpace, key)
 
-	c, err := rl.CountEventsBeforeCurrent(ctx, bucketName, timestamp)
+	decision := &Decision{
+		Limit:     maxInInterval,
+		Window:    interval,
+		NameSpace: nameSpace,
+		Key:       key,
+		ResetAt:   time.UnixMilli(timestamp).Add(interval),
+	}
+
+	if exceedsLimit(cost, maxInInterval) {
+		decision.RetryAfter = interval
+		return decision, nil
+	}
+
+	if as, ok := rl.EventStorage.(AtomicEventStorage); ok {
+		return atomicDecide(ctx, as, bucketName, timestamp, interval, cost, decision)
+	}
 
+	err := rl.RemoveExpiredEvents(ctx, bucketName, timestamp, interval)
 	if err != nil {
-		return false, fmt.Errorf("error when counting the number of events: %w", err)
+		return nil, fmt.Errorf("error when removing expired events: %w", err)
 	}
 
-	if c < maxInInterval {
-		err := rl.AddEvent(ctx, bucketName, timestamp)
+	events, c, err := rl.eventsBeforeCurrent(ctx, bucketName, timestamp)
+
+	if err != nil {
+		return nil, fmt.Errorf("error when counting the number of events: %w", err)
+	}
+
+	if c+cost <= maxInInterval {
+		err := rl.AddEvent(ctx, bucketName, timestamp, cost)
 		if err != nil {
-			return false, fmt.Errorf("error when adding event: %w", err)
+			return nil, fmt.Errorf("error when adding event: %w", err)
+		}
+		err = rl.EventStorage.SetEventTLL(ctx, bucketName, interval)
+		if err != nil {
+			return nil, fmt.Errorf("error when setting the events ttl: %w", err)
+		}
+		decision.Allowed = true
+		decision.Remaining = maxInInterval - c - cost
+		return decision, nil
+	}
+
+	decision.Remaining = max(0, maxInInterval-c)
+	if len(events) > 0 {
+		retry := retryEvent(events, c+cost-maxInInterval)
+		decision.ResetAt = time.UnixMilli(int64(events[0].Score)).Add(interval)
+		decision.RetryAfter = max(0, time.UnixMilli(int64(retry.Score)).Add(interval).Sub(time.UnixMilli(timestamp)))
+		return decision, nil
+	}
+
+	decision.RetryAfter = max(0, decision.ResetAt.Sub(time.UnixMilli(timestamp)))
+	return decision, nil
+}
+
+// retryEvent returns the event after which at least weight has left the
+// window, or the newest one when the events weigh less than that.
+func retryEvent(events []*Event, weight int64) *Event {
+	var released int64
+	for _, event := range events {
+		released += EventWeight(event.Value)
+		if released >= weight {
+			return event
 		}
-		return false, nil
 	}
+	return events[len(events)-1]
+}
 
-	err = rl.RemoveExpiredEvents(ctx, bucketName, float64(timestamp), float64(IntervalSecund))
+func atomicDecide(ctx context.Context, as AtomicEventStorage, key string, timestamp int64, interval time.Duration, cost int64, decision *Decision) (*Decision, error) {
+	windowStart := float64(timestamp - interval.Milliseconds())
 
+	window, err := as.AddWithinLimit(ctx, key, []*Event{newEvent(timestamp, cost)}, windowStart, decision.Limit, interval)
 	if err != nil {
-		return false, fmt.Errorf("error when removing expired events: %w", err)
+		return nil, fmt.Errorf("error when adding event within limit: %w", err)
+	}
+
+	decision.Allowed = window.Added
+	decision.Remaining = max(0, decision.Limit-window.Count)
+	if window.Count > 0 {
+		decision.ResetAt = time.UnixMilli(int64(window.OldestScore)).Add(interval)
+	}
+	if !window.Added {
+		retryAt := decision.ResetAt
+		if window.Count > 0 {
+			retryAt = time.UnixMilli(int64(window.RetryScore)).Add(interval)
+		}
+		decision.RetryAfter = max(0, retryAt.Sub(time.UnixMilli(timestamp)))
 	}
 
-	return true, nil
+	return decision, nil
+}
+
+// newEvent returns the single event stored for a request, its value carries
+// the cost when it is above 1, see EventWeight.
+func newEvent(timestamp, cost int64) *Event {
+	id := uuid.New().String()
+	value := fmt.Sprintf("event:%s:%d", id, timestamp)
+	if cost > 1 {
+		value = fmt.Sprintf("%s:%d", value, cost)
+	}
+	return &Event{
+		Score: float64(timestamp),
+		Value: value,
+	}
+}
+
+// exceedsLimit reports a cost that can never be allowed, such requests are
+// denied before any event is created or any arithmetic is done on the cost.
+func exceedsLimit(cost, limit int64) bool {
+	return cost > limit || cost > MaxCost
+}
+
+// requestCost is the Cost of the options, at least 1.
+func requestCost(opt *Options) int64 {
+	if opt == nil || opt.Cost <= 0 {
+		return 1
+	}
+	return opt.Cost
 }
 
 func chooseString(defaultVal string, opt *Options, optSelector func(*Options) string) string {
@@ -134,3 +266,13 @@ func chooseInt64(defaultVal int64, opt *Options, optSelector func(*Options) int6
 	}
 	return defaultVal
 }
+
+func chooseDuration(defaultVal time.Duration, opt *Options, optSelector func(*Options) time.Duration) time.Duration {
+	if opt != nil {
+		optVal := optSelector(opt)
+		if optVal != 0 {
+			return optVal
+		}
+	}
+	return defaultVal
+}
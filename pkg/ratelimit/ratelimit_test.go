@@ -3,10 +3,13 @@ package ratelimit_test
 import (
 	"context"
 	"errors"
+	"fmt"
 	"testing"
 	"time"
 
 	"github.com/GeovaneCavalcante/rate-limit-api/pkg/ratelimit"
+	"github.com/GeovaneCavalcante/rate-limit-api/pkg/ratelimit/clocktest"
+	"github.com/GeovaneCavalcante/rate-limit-api/pkg/ratelimit/memory"
 	mock_storage "github.com/GeovaneCavalcante/rate-limit-api/pkg/ratelimit/mock"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/suite"
@@ -15,17 +18,20 @@ import (
 
 type RateLimiterTestSuite struct {
 	suite.Suite
-	EventStorageMock *mock_storage.MockEventStorageInterface
+	EventStorageMock       *mock_storage.MockEventStorageInterface
+	AtomicEventStorageMock *mock_storage.MockAtomicEventStorage
 }
 
 func (suite *RateLimiterTestSuite) SetupTest() {
 	ctrl := gomock.NewController(suite.T())
 	suite.EventStorageMock = mock_storage.NewMockEventStorageInterface(ctrl)
+	suite.AtomicEventStorageMock = mock_storage.NewMockAtomicEventStorage(ctrl)
 }
 
 func (suite *RateLimiterTestSuite) TestLimiter() {
-	suite.Run("should return true when the number of events is greater than the maximum allowed", func() {
-		suite.EventStorageMock.EXPECT().CountRange(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(int64(0), errors.New("error"))
+	suite.Run("should return an error when counting the events fails", func() {
+		suite.EventStorageMock.EXPECT().RemoveRangeByScore(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(nil)
+		suite.EventStorageMock.EXPECT().FindRangeWithScores(gomock.Any(), gomock.Any(), int64(0), int64(-1)).Return(nil, errors.New("error"))
 
 		rl, err := ratelimit.New(suite.EventStorageMock, "test", 1, 1)
 		if err != nil {
@@ -37,30 +43,9 @@ func (suite *RateLimiterTestSuite) TestLimiter() {
 		assert.False(suite.T(), value)
 	})
 
-	suite.Run("should return false when the number of events is greater than the maximum allowed", func() {
-		suite.EventStorageMock.EXPECT().CountRange(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(int64(2), nil)
-		suite.EventStorageMock.EXPECT().FindRangeWithScores(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(nil, errors.New("error"))
-
-		rl, err := ratelimit.New(suite.EventStorageMock, "test", 1, 1)
-		if err != nil {
-			suite.FailNow(err.Error())
-		}
-
-		value, err := rl.Limiter(context.Background(), "test", nil)
-		assert.Equal(suite.T(), err.Error(), "error when removing expired events: error when finding the oldest event: error")
-		assert.False(suite.T(), value)
-	})
-
-	suite.Run("should return true when user escapes the limitation", func() {
-
-		suite.EventStorageMock.EXPECT().CountRange(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(int64(2), nil)
-		suite.EventStorageMock.EXPECT().FindRangeWithScores(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return([]*ratelimit.Event{
-			{
-				ID:    "test",
-				Score: float64(time.Now().Unix() + 1),
-				Value: "test",
-			},
-		}, nil)
+	suite.Run("should return true when the number of events reaches the maximum allowed", func() {
+		suite.EventStorageMock.EXPECT().RemoveRangeByScore(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(nil)
+		suite.EventStorageMock.EXPECT().FindRangeWithScores(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(recentEvents(2), nil)
 
 		rl, err := ratelimit.New(suite.EventStorageMock, "test", 1, 1)
 		if err != nil {
@@ -73,8 +58,8 @@ func (suite *RateLimiterTestSuite) TestLimiter() {
 	})
 
 	suite.Run("should return an error when adding an event fails", func() {
-
-		suite.EventStorageMock.EXPECT().CountRange(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(int64(2), nil)
+		suite.EventStorageMock.EXPECT().RemoveRangeByScore(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(nil)
+		suite.EventStorageMock.EXPECT().FindRangeWithScores(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(recentEvents(2), nil)
 		suite.EventStorageMock.EXPECT().Add(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil, errors.New("error"))
 
 		rl, err := ratelimit.New(suite.EventStorageMock, "test", 6, 1)
@@ -89,9 +74,10 @@ func (suite *RateLimiterTestSuite) TestLimiter() {
 	})
 
 	suite.Run("should return an true when adding an event", func() {
-
-		suite.EventStorageMock.EXPECT().CountRange(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(int64(2), nil)
+		suite.EventStorageMock.EXPECT().RemoveRangeByScore(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(nil)
+		suite.EventStorageMock.EXPECT().FindRangeWithScores(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(recentEvents(2), nil)
 		suite.EventStorageMock.EXPECT().Add(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil, nil)
+		suite.EventStorageMock.EXPECT().SetEventTLL(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil)
 
 		rl, err := ratelimit.New(suite.EventStorageMock, "test", 6, 1)
 		if err != nil {
@@ -99,41 +85,65 @@ func (suite *RateLimiterTestSuite) TestLimiter() {
 		}
 
 		value, err := rl.Limiter(context.Background(), "test", &ratelimit.Options{
-			NameSpace:      "test",
-			MaxInInterval:  6,
-			IntervalSecund: 1,
+			NameSpace:     "test",
+			MaxInInterval: 6,
+			Interval:      time.Second,
 		})
 
 		assert.NoError(suite.T(), err)
 		assert.False(suite.T(), value)
 	})
 
-	suite.Run("should return true when user escapes the limitation", func() {
+	suite.Run("should trim the events older than the window before counting", func() {
+		clock := clocktest.NewFakeClock(time.UnixMilli(1700000000000))
+		suite.EventStorageMock.EXPECT().RemoveRangeByScore(gomock.Any(), "test:key", "min", "1699999940000").Return(nil)
+		suite.EventStorageMock.EXPECT().FindRangeWithScores(gomock.Any(), "test:key", gomock.Any(), gomock.Any()).Return(recentEvents(0), nil)
+		suite.EventStorageMock.EXPECT().Add(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil, nil)
+		suite.EventStorageMock.EXPECT().SetEventTLL(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil)
+
+		rl, err := ratelimit.New(suite.EventStorageMock, "test", 1, 60*time.Second, ratelimit.WithClock(clock))
+		if err != nil {
+			suite.FailNow(err.Error())
+		}
+
+		value, err := rl.Limiter(context.Background(), "key", nil)
+		assert.NoError(suite.T(), err)
+		assert.False(suite.T(), value)
+	})
 
-		suite.EventStorageMock.EXPECT().CountRange(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(int64(2), nil)
-		suite.EventStorageMock.EXPECT().FindRangeWithScores(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return([]*ratelimit.Event{}, nil)
+	suite.Run("should refresh the ttl of the events with the window", func() {
+		suite.EventStorageMock.EXPECT().RemoveRangeByScore(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(nil)
+		suite.EventStorageMock.EXPECT().FindRangeWithScores(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(recentEvents(0), nil)
+		suite.EventStorageMock.EXPECT().Add(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil, nil)
+		suite.EventStorageMock.EXPECT().SetEventTLL(gomock.Any(), "test:key", 250*time.Millisecond).Return(nil)
 
-		rl, err := ratelimit.New(suite.EventStorageMock, "test", 1, 1)
+		rl, err := ratelimit.New(suite.EventStorageMock, "test", 1, 250*time.Millisecond)
 		if err != nil {
 			suite.FailNow(err.Error())
 		}
 
-		value, err := rl.Limiter(context.Background(), "test", nil)
+		value, err := rl.Limiter(context.Background(), "key", nil)
 		assert.NoError(suite.T(), err)
-		assert.True(suite.T(), value)
+		assert.False(suite.T(), value)
 	})
 
-	suite.Run("should return error when there is an error in removal", func() {
+	suite.Run("should return an error when setting the ttl fails", func() {
+		suite.EventStorageMock.EXPECT().RemoveRangeByScore(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(nil)
+		suite.EventStorageMock.EXPECT().FindRangeWithScores(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(recentEvents(0), nil)
+		suite.EventStorageMock.EXPECT().Add(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil, nil)
+		suite.EventStorageMock.EXPECT().SetEventTLL(gomock.Any(), gomock.Any(), gomock.Any()).Return(errors.New("error"))
 
-		suite.EventStorageMock.EXPECT().CountRange(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(int64(2), nil)
-		suite.EventStorageMock.EXPECT().FindRangeWithScores(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return([]*ratelimit.Event{
-			{
-				ID:    "test",
-				Score: 0,
-				Value: "test",
-			},
-		}, nil)
+		rl, err := ratelimit.New(suite.EventStorageMock, "test", 1, time.Second)
+		if err != nil {
+			suite.FailNow(err.Error())
+		}
 
+		value, err := rl.Limiter(context.Background(), "key", nil)
+		assert.Equal(suite.T(), "error when setting the events ttl: error", err.Error())
+		assert.False(suite.T(), value)
+	})
+
+	suite.Run("should return error when there is an error in removal", func() {
 		suite.EventStorageMock.EXPECT().RemoveRangeByScore(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(errors.New("error"))
 
 		rl, err := ratelimit.New(suite.EventStorageMock, "test", 1, 1)
@@ -147,6 +157,329 @@ func (suite *RateLimiterTestSuite) TestLimiter() {
 	})
 }
 
+func (suite *RateLimiterTestSuite) TestAtomicLimiter() {
+	suite.Run("should return false when the event is added within the limit", func() {
+		suite.AtomicEventStorageMock.EXPECT().AddWithinLimit(gomock.Any(), "test:key", gomock.Any(), gomock.Any(), int64(2), 60*time.Second).Return(&ratelimit.EventWindow{Count: 1, Added: true}, nil)
+
+		rl, err := ratelimit.New(suite.AtomicEventStorageMock, "test", 2, 60*time.Second)
+		if err != nil {
+			suite.FailNow(err.Error())
+		}
+
+		value, err := rl.Limiter(context.Background(), "key", nil)
+		assert.NoError(suite.T(), err)
+		assert.False(suite.T(), value)
+	})
+
+	suite.Run("should return true when the limit is reached", func() {
+		suite.AtomicEventStorageMock.EXPECT().AddWithinLimit(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(&ratelimit.EventWindow{Count: 2, Added: false}, nil)
+
+		rl, err := ratelimit.New(suite.AtomicEventStorageMock, "test", 2, 60*time.Second)
+		if err != nil {
+			suite.FailNow(err.Error())
+		}
+
+		value, err := rl.Limiter(context.Background(), "key", nil)
+		assert.NoError(suite.T(), err)
+		assert.True(suite.T(), value)
+	})
+
+	suite.Run("should use the window of the options", func() {
+		suite.AtomicEventStorageMock.EXPECT().AddWithinLimit(gomock.Any(), "token:key", gomock.Any(), gomock.Any(), int64(5), 30*time.Second).
+			DoAndReturn(func(_ context.Context, _ string, events []*ratelimit.Event, windowStart float64, _ int64, _ time.Duration) (*ratelimit.EventWindow, error) {
+				assert.Len(suite.T(), events, 1)
+				assert.Equal(suite.T(), events[0].Score-30000, windowStart)
+				return &ratelimit.EventWindow{Count: 1, Added: true}, nil
+			})
+
+		rl, err := ratelimit.New(suite.AtomicEventStorageMock, "test", 2, 60*time.Second)
+		if err != nil {
+			suite.FailNow(err.Error())
+		}
+
+		value, err := rl.Limiter(context.Background(), "key", &ratelimit.Options{
+			NameSpace:     "token",
+			MaxInInterval: 5,
+			Interval:      30 * time.Second,
+		})
+		assert.NoError(suite.T(), err)
+		assert.False(suite.T(), value)
+	})
+
+	suite.Run("should return an error when the atomic storage fails", func() {
+		suite.AtomicEventStorageMock.EXPECT().AddWithinLimit(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(nil, errors.New("error"))
+
+		rl, err := ratelimit.New(suite.AtomicEventStorageMock, "test", 2, 60*time.Second)
+		if err != nil {
+			suite.FailNow(err.Error())
+		}
+
+		value, err := rl.Limiter(context.Background(), "key", nil)
+		assert.Equal(suite.T(), "error when adding event within limit: error", err.Error())
+		assert.False(suite.T(), value)
+	})
+}
+
+func (suite *RateLimiterTestSuite) TestDecide() {
+	suite.Run("should return the remaining requests when the event is added", func() {
+		suite.EventStorageMock.EXPECT().RemoveRangeByScore(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(nil)
+		suite.EventStorageMock.EXPECT().FindRangeWithScores(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(recentEvents(2), nil)
+		suite.EventStorageMock.EXPECT().Add(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil, nil)
+		suite.EventStorageMock.EXPECT().SetEventTLL(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil)
+
+		rl, err := ratelimit.New(suite.EventStorageMock, "test", 6, 60*time.Second)
+		if err != nil {
+			suite.FailNow(err.Error())
+		}
+
+		decision, err := rl.Decide(context.Background(), "key", nil)
+		assert.NoError(suite.T(), err)
+		assert.True(suite.T(), decision.Allowed)
+		assert.Equal(suite.T(), int64(6), decision.Limit)
+		assert.Equal(suite.T(), int64(3), decision.Remaining)
+		assert.Equal(suite.T(), time.Duration(0), decision.RetryAfter)
+		assert.Equal(suite.T(), "test", decision.NameSpace)
+		assert.Equal(suite.T(), "key", decision.Key)
+	})
+
+	suite.Run("should compute the reset from the oldest event of the atomic storage", func() {
+		now := time.Now().UnixMilli()
+		suite.AtomicEventStorageMock.EXPECT().AddWithinLimit(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).
+			Return(&ratelimit.EventWindow{Count: 2, Added: false, OldestScore: float64(now - 20000), RetryScore: float64(now - 20000)}, nil)
+
+		rl, err := ratelimit.New(suite.AtomicEventStorageMock, "test", 2, 60*time.Second)
+		if err != nil {
+			suite.FailNow(err.Error())
+		}
+
+		decision, err := rl.Decide(context.Background(), "key", nil)
+		assert.NoError(suite.T(), err)
+		assert.False(suite.T(), decision.Allowed)
+		assert.Equal(suite.T(), int64(0), decision.Remaining)
+		assert.Equal(suite.T(), time.UnixMilli(now+40000), decision.ResetAt)
+		assert.InDelta(suite.T(), float64(40*time.Second), float64(decision.RetryAfter), float64(time.Second))
+	})
+}
+
+func (suite *RateLimiterTestSuite) TestWindowExpiry() {
+	start := time.Unix(1700000000, 0)
+
+	newLimiter := func() (*ratelimit.RateLimiter, *clocktest.FakeClock) {
+		clock := clocktest.NewFakeClock(start)
+		es := memory.NewMemoryEventStorage(0, memory.WithClock(clock))
+		rl, err := ratelimit.New(es, "test", 2, 60*time.Second, ratelimit.WithClock(clock))
+		if err != nil {
+			suite.FailNow(err.Error())
+		}
+		return rl, clock
+	}
+
+	exhaust := func(rl *ratelimit.RateLimiter) {
+		for i := 0; i < 2; i++ {
+			value, err := rl.Limiter(context.Background(), "key", nil)
+			assert.NoError(suite.T(), err)
+			assert.False(suite.T(), value)
+		}
+	}
+
+	suite.Run("should still limit 59 seconds after the window started", func() {
+		rl, clock := newLimiter()
+		exhaust(rl)
+
+		clock.Advance(59 * time.Second)
+
+		decision, err := rl.Decide(context.Background(), "key", nil)
+		assert.NoError(suite.T(), err)
+		assert.False(suite.T(), decision.Allowed)
+		assert.Equal(suite.T(), time.Second, decision.RetryAfter)
+		assert.Equal(suite.T(), start.Add(60*time.Second), decision.ResetAt)
+	})
+
+	suite.Run("should allow again 61 seconds after the window started", func() {
+		rl, clock := newLimiter()
+		exhaust(rl)
+
+		clock.Advance(61 * time.Second)
+
+		decision, err := rl.Decide(context.Background(), "key", nil)
+		assert.NoError(suite.T(), err)
+		assert.True(suite.T(), decision.Allowed)
+		assert.Equal(suite.T(), int64(1), decision.Remaining)
+	})
+}
+
+func (suite *RateLimiterTestSuite) TestSubSecondWindow() {
+	start := time.Unix(1700000000, 0)
+	clock := clocktest.NewFakeClock(start)
+	es := memory.NewMemoryEventStorage(0, memory.WithClock(clock))
+	rl, err := ratelimit.New(es, "test", 10, 100*time.Millisecond, ratelimit.WithClock(clock))
+	if err != nil {
+		suite.FailNow(err.Error())
+	}
+
+	for i := 0; i < 10; i++ {
+		value, err := rl.Limiter(context.Background(), "key", nil)
+		assert.NoError(suite.T(), err)
+		assert.False(suite.T(), value)
+	}
+
+	decision, err := rl.Decide(context.Background(), "key", nil)
+	assert.NoError(suite.T(), err)
+	assert.False(suite.T(), decision.Allowed)
+	assert.Equal(suite.T(), 100*time.Millisecond, decision.Window)
+	assert.Equal(suite.T(), 100*time.Millisecond, decision.RetryAfter)
+
+	clock.Advance(50 * time.Millisecond)
+
+	decision, err = rl.Decide(context.Background(), "key", nil)
+	assert.NoError(suite.T(), err)
+	assert.False(suite.T(), decision.Allowed)
+	assert.Equal(suite.T(), 50*time.Millisecond, decision.RetryAfter)
+
+	clock.Advance(51 * time.Millisecond)
+
+	decision, err = rl.Decide(context.Background(), "key", nil)
+	assert.NoError(suite.T(), err)
+	assert.True(suite.T(), decision.Allowed)
+	assert.Equal(suite.T(), int64(9), decision.Remaining)
+}
+
+// eventStorage hides AddWithinLimit so the limiter takes the non atomic path.
+type eventStorage struct {
+	ratelimit.EventStorageInterface
+}
+
+func (suite *RateLimiterTestSuite) TestSlidingExpiry() {
+	start := time.Unix(1700000000, 0)
+
+	paths := map[string]func(*memory.MemoryEventStorage) ratelimit.EventStorageInterface{
+		"atomic":     func(es *memory.MemoryEventStorage) ratelimit.EventStorageInterface { return es },
+		"non atomic": func(es *memory.MemoryEventStorage) ratelimit.EventStorageInterface { return eventStorage{es} },
+	}
+
+	for name, storage := range paths {
+		suite.Run(name+" should only release the events that left the window", func() {
+			clock := clocktest.NewFakeClock(start)
+			es := memory.NewMemoryEventStorage(0, memory.WithClock(clock))
+			rl, err := ratelimit.New(storage(es), "test", 2, 60*time.Second, ratelimit.WithClock(clock))
+			if err != nil {
+				suite.FailNow(err.Error())
+			}
+
+			decide := func() *ratelimit.Decision {
+				decision, err := rl.Decide(context.Background(), "key", nil)
+				if err != nil {
+					suite.FailNow(err.Error())
+				}
+				return decision
+			}
+
+			assert.True(suite.T(), decide().Allowed)
+			clock.Advance(30 * time.Second)
+			assert.True(suite.T(), decide().Allowed)
+
+			clock.Advance(15 * time.Second)
+			decision := decide()
+			assert.False(suite.T(), decision.Allowed)
+			assert.Equal(suite.T(), 15*time.Second, decision.RetryAfter)
+
+			clock.Advance(16 * time.Second)
+			decision = decide()
+			assert.True(suite.T(), decision.Allowed)
+			assert.Equal(suite.T(), int64(0), decision.Remaining)
+
+			decision = decide()
+			assert.False(suite.T(), decision.Allowed)
+			assert.Equal(suite.T(), start.Add(90*time.Second), decision.ResetAt)
+			assert.Equal(suite.T(), 29*time.Second, decision.RetryAfter)
+		})
+
+		suite.Run(name+" should not count the denied requests", func() {
+			clock := clocktest.NewFakeClock(start)
+			es := memory.NewMemoryEventStorage(0, memory.WithClock(clock))
+			rl, err := ratelimit.New(storage(es), "test", 1, time.Second, ratelimit.WithClock(clock))
+			if err != nil {
+				suite.FailNow(err.Error())
+			}
+
+			for i := 0; i < 10; i++ {
+				decision, err := rl.Decide(context.Background(), "key", nil)
+				assert.NoError(suite.T(), err)
+				assert.Equal(suite.T(), i%2 == 0, decision.Allowed)
+				clock.Advance(600 * time.Millisecond)
+			}
+		})
+	}
+}
+
+func (suite *RateLimiterTestSuite) TestWeightedEvents() {
+	suite.Run("should store a single event carrying the cost", func() {
+		suite.AtomicEventStorageMock.EXPECT().AddWithinLimit(gomock.Any(), "test:key", gomock.Any(), gomock.Any(), int64(10), gomock.Any()).
+			DoAndReturn(func(_ context.Context, _ string, events []*ratelimit.Event, _ float64, _ int64, _ time.Duration) (*ratelimit.EventWindow, error) {
+				if assert.Len(suite.T(), events, 1) {
+					assert.Equal(suite.T(), int64(7), ratelimit.EventWeight(events[0].Value))
+				}
+				return &ratelimit.EventWindow{Count: 7, Added: true}, nil
+			})
+
+		rl, err := ratelimit.New(suite.AtomicEventStorageMock, "test", 10, time.Minute)
+		if err != nil {
+			suite.FailNow(err.Error())
+		}
+
+		decision, err := rl.Decide(context.Background(), "key", &ratelimit.Options{Cost: 7})
+		assert.NoError(suite.T(), err)
+		assert.True(suite.T(), decision.Allowed)
+		assert.Equal(suite.T(), int64(3), decision.Remaining)
+	})
+
+	suite.Run("should sum the weight of the events without atomic storage", func() {
+		now := time.Now().UnixMilli()
+		suite.EventStorageMock.EXPECT().RemoveRangeByScore(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(nil)
+		suite.EventStorageMock.EXPECT().FindRangeWithScores(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return([]*ratelimit.Event{
+			{Score: float64(now - 30000), Value: "event:a:1:3"},
+			{Score: float64(now - 20000), Value: "event:b:2:4"},
+			{Score: float64(now - 10000), Value: "event:c:3"},
+		}, nil)
+
+		rl, err := ratelimit.New(suite.EventStorageMock, "test", 10, time.Minute)
+		if err != nil {
+			suite.FailNow(err.Error())
+		}
+
+		decision, err := rl.Decide(context.Background(), "key", &ratelimit.Options{Cost: 6})
+		assert.NoError(suite.T(), err)
+		assert.False(suite.T(), decision.Allowed)
+		assert.Equal(suite.T(), int64(2), decision.Remaining)
+		assert.InDelta(suite.T(), 40*time.Second, decision.RetryAfter, float64(time.Second))
+	})
+}
+
+func TestEventWeight(t *testing.T) {
+	tests := map[string]int64{
+		"event:id:1700000000000":      1,
+		"event:id:1700000000000:25":   25,
+		"event:id:1700000000000:0":    1,
+		"event:id:1700000000000:-3":   1,
+		"event:id:1700000000000:cost": 1,
+		"other:id:1700000000000:25":   1,
+		"a":                           1,
+	}
+	for value, weight := range tests {
+		assert.Equal(t, weight, ratelimit.EventWeight(value), value)
+	}
+}
+
+// recentEvents returns n events of weight 1 scored a second ago.
+func recentEvents(n int) []*ratelimit.Event {
+	score := float64(time.Now().Add(-time.Second).UnixMilli())
+	events := make([]*ratelimit.Event, 0, n)
+	for i := 0; i < n; i++ {
+		events = append(events, &ratelimit.Event{Score: score, Value: fmt.Sprintf("event:%d:%d", i, int64(score))})
+	}
+	return events
+}
+
 func TestSuite(t *testing.T) {
 	suite.Run(t, new(RateLimiterTestSuite))
 }
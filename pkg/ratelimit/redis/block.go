@@ -0,0 +1,40 @@
+package redis
+
+import (
+	"context"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+type RedisBlockStorage struct {
+	RedisClient *redis.Client
+}
+
+func NewRedisBlockStorage(rc *redis.Client) *RedisBlockStorage {
+	return &RedisBlockStorage{
+		RedisClient: rc,
+	}
+}
+
+// Block expires the marker at until with PEXPIREAT, so it does not depend on
+// the clock of the caller. Redis deletes the marker at once when until has
+// passed.
+func (rbs *RedisBlockStorage) Block(ctx context.Context, key string, until time.Time) error {
+	pipe := rbs.RedisClient.TxPipeline()
+	pipe.Set(ctx, key, until.UnixNano(), 0)
+	pipe.PExpireAt(ctx, key, until)
+	_, err := pipe.Exec(ctx)
+	return err
+}
+
+func (rbs *RedisBlockStorage) BlockedUntil(ctx context.Context, key string) (time.Time, error) {
+	until, err := rbs.RedisClient.Get(ctx, key).Int64()
+	if err == redis.Nil {
+		return time.Time{}, nil
+	}
+	if err != nil {
+		return time.Time{}, err
+	}
+	return time.Unix(0, until), nil
+}
@@ -0,0 +1,53 @@
+package redis
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRedisBlockStorage(t *testing.T) {
+	ctx := context.Background()
+	now := time.Unix(1700000000, 0)
+
+	t.Run("should block the key until the given time", func(t *testing.T) {
+		mr, rc := newTestClient(t)
+		mr.SetTime(now)
+		s := NewRedisBlockStorage(rc)
+
+		until, err := s.BlockedUntil(ctx, "key")
+		assert.NoError(t, err)
+		assert.True(t, until.IsZero())
+
+		blockedUntil := now.Add(time.Minute)
+		assert.NoError(t, s.Block(ctx, "key", blockedUntil))
+
+		until, err = s.BlockedUntil(ctx, "key")
+		assert.NoError(t, err)
+		assert.True(t, blockedUntil.Equal(until))
+		assert.Equal(t, time.Minute, mr.TTL("key"))
+
+		mr.FastForward(time.Minute)
+		until, err = s.BlockedUntil(ctx, "key")
+		assert.NoError(t, err)
+		assert.True(t, until.IsZero())
+	})
+
+	t.Run("should expire the block from the given time and not the local clock", func(t *testing.T) {
+		mr, rc := newTestClient(t)
+		mr.SetTime(now)
+		s := NewRedisBlockStorage(rc)
+
+		// now is years behind the wall clock, the block must still last a
+		// minute.
+		assert.NoError(t, s.Block(ctx, "key", now.Add(time.Minute)))
+		assert.Equal(t, time.Minute, mr.TTL("key"))
+
+		assert.NoError(t, s.Block(ctx, "past", now.Add(-time.Second)))
+		until, err := s.BlockedUntil(ctx, "past")
+		assert.NoError(t, err)
+		assert.True(t, until.IsZero())
+	})
+}
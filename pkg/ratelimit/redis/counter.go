@@ -0,0 +1,91 @@
+package redis
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/GeovaneCavalcante/rate-limit-api/pkg/ratelimit"
+	"github.com/go-redis/redis/v8"
+)
+
+// incrementWithinLimitScript reads the previous and current window counters
+// and only increments the current one when the weighted estimate fits in the
+// limit, in a single round trip so concurrent replicas can not overshoot it.
+var incrementWithinLimitScript = redis.NewScript(`
+local previous = tonumber(redis.call("GET", KEYS[1]) or "0")
+local current = tonumber(redis.call("GET", KEYS[2]) or "0")
+local weight = tonumber(ARGV[1])
+local amount = tonumber(ARGV[2])
+local limit = tonumber(ARGV[3])
+local ttl = tonumber(ARGV[4])
+
+if previous * weight + current + amount > limit then
+	return {previous, current, 0}
+end
+
+redis.call("INCRBY", KEYS[2], amount)
+if ttl > 0 then
+	redis.call("PEXPIRE", KEYS[2], ttl)
+end
+return {previous, current, 1}
+`)
+
+type RedisCounterStorage struct {
+	RedisClient *redis.Client
+}
+
+func NewRedisCounterStorage(rc *redis.Client) *RedisCounterStorage {
+	return &RedisCounterStorage{
+		RedisClient: rc,
+	}
+}
+
+func (rcs *RedisCounterStorage) GetCounters(ctx context.Context, keys ...string) ([]int64, error) {
+	values, err := rcs.RedisClient.MGet(ctx, keys...).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	counters := make([]int64, len(values))
+	for i, value := range values {
+		s, ok := value.(string)
+		if !ok {
+			continue
+		}
+		counters[i], err = strconv.ParseInt(s, 10, 64)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return counters, nil
+}
+
+func (rcs *RedisCounterStorage) IncrementCounter(ctx context.Context, key string, amount int64, ttl time.Duration) (int64, error) {
+	pipe := rcs.RedisClient.TxPipeline()
+	incr := pipe.IncrBy(ctx, key, amount)
+	pipe.PExpire(ctx, key, ttl)
+	_, err := pipe.Exec(ctx)
+	if err != nil {
+		return 0, err
+	}
+	return incr.Val(), nil
+}
+
+func (rcs *RedisCounterStorage) IncrementWithinLimit(ctx context.Context, previousKey, currentKey string, previousWeight float64, amount, limit int64, ttl time.Duration) (*ratelimit.CounterWindow, error) {
+	weight := strconv.FormatFloat(previousWeight, 'f', -1, 64)
+	values, err := incrementWithinLimitScript.Run(ctx, rcs.RedisClient, []string{previousKey, currentKey}, weight, amount, limit, ttl.Milliseconds()).Int64Slice()
+	if err != nil {
+		return nil, err
+	}
+	if len(values) != 3 {
+		return nil, fmt.Errorf("unexpected increment within limit reply: %v", values)
+	}
+
+	return &ratelimit.CounterWindow{
+		Previous: values[0],
+		Current:  values[1],
+		Added:    values[2] == 1,
+	}, nil
+}
@@ -0,0 +1,98 @@
+package redis
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// takeTATScript mirrors ratelimit.GCRALimiter in a single round trip. The
+// nanosecond timestamps do not fit the precision of the Lua numbers, so they
+// are split in seconds and nanoseconds.
+var takeTATScript = redis.NewScript(`
+local function parse(s)
+	if #s <= 9 then
+		return 0, tonumber(s)
+	end
+	return tonumber(string.sub(s, 1, -10)), tonumber(string.sub(s, -9))
+end
+
+local function add(as, an, bs, bn)
+	local s, n = as + bs, an + bn
+	if n >= 1000000000 then
+		s, n = s + 1, n - 1000000000
+	end
+	return s, n
+end
+
+local function less(as, an, bs, bn)
+	return as < bs or (as == bs and an < bn)
+end
+
+local function format(s, n)
+	if s == 0 then
+		return string.format("%d", n)
+	end
+	return string.format("%d%09d", s, n)
+end
+
+local key = KEYS[1]
+local nowS, nowN = parse(ARGV[1])
+local incS, incN = parse(ARGV[2])
+local limitS, limitN = parse(ARGV[3])
+
+local tatS, tatN = nowS, nowN
+local stored = redis.call("GET", key)
+if stored then
+	local storedS, storedN = parse(stored)
+	if storedS == nil or storedN == nil then
+		return redis.error_reply("invalid theoretical arrival time " .. stored)
+	end
+	if less(tatS, tatN, storedS, storedN) then
+		tatS, tatN = storedS, storedN
+	end
+end
+
+local newS, newN = add(tatS, tatN, incS, incN)
+if less(limitS, limitN, newS, newN) then
+	return {format(tatS, tatN), 0}
+end
+
+local ttl = (newS - nowS) * 1000 + math.ceil((newN - nowN) / 1000000)
+redis.call("SET", key, format(newS, newN), "PX", math.max(1, ttl))
+return {format(tatS, tatN), 1}
+`)
+
+type RedisTATStorage struct {
+	RedisClient *redis.Client
+}
+
+func NewRedisTATStorage(rc *redis.Client) *RedisTATStorage {
+	return &RedisTATStorage{
+		RedisClient: rc,
+	}
+}
+
+func (rts *RedisTATStorage) TakeTAT(ctx context.Context, key string, now, increment, limit int64) (int64, bool, error) {
+	result, err := takeTATScript.Run(ctx, rts.RedisClient, []string{key},
+		strconv.FormatInt(now, 10),
+		strconv.FormatInt(increment, 10),
+		strconv.FormatInt(limit, 10),
+	).Slice()
+	if err != nil {
+		return 0, false, err
+	}
+	if len(result) != 2 {
+		return 0, false, fmt.Errorf("unexpected take tat script result: %v", result)
+	}
+
+	tat, err := strconv.ParseInt(fmt.Sprint(result[0]), 10, 64)
+	if err != nil {
+		return 0, false, err
+	}
+	allowed, _ := result[1].(int64)
+
+	return tat, allowed == 1, nil
+}
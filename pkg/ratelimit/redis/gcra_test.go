@@ -0,0 +1,101 @@
+package redis
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRedisTATStorage(t *testing.T) {
+	ctx := context.Background()
+	now := time.Unix(1700000000, 0).UnixNano()
+
+	t.Run("should take now as the arrival time of a missing key", func(t *testing.T) {
+		mr, rc := newTestClient(t)
+		s := NewRedisTATStorage(rc)
+
+		tat, allowed, err := s.TakeTAT(ctx, "key", now, int64(time.Second), now+int64(time.Second))
+		assert.NoError(t, err)
+		assert.True(t, allowed)
+		assert.Equal(t, now, tat)
+
+		stored, err := mr.Get("key")
+		assert.NoError(t, err)
+		assert.Equal(t, "1700000001000000000", stored)
+	})
+
+	t.Run("should not store the arrival time over the limit", func(t *testing.T) {
+		mr, rc := newTestClient(t)
+		s := NewRedisTATStorage(rc)
+
+		_, _, err := s.TakeTAT(ctx, "key", now, int64(time.Second), now+int64(time.Second))
+		assert.NoError(t, err)
+
+		tat, allowed, err := s.TakeTAT(ctx, "key", now, int64(time.Second), now+int64(time.Second))
+		assert.NoError(t, err)
+		assert.False(t, allowed)
+		assert.Equal(t, now+int64(time.Second), tat)
+
+		stored, _ := mr.Get("key")
+		assert.Equal(t, "1700000001000000000", stored)
+	})
+
+	t.Run("should keep the nanosecond precision", func(t *testing.T) {
+		mr, rc := newTestClient(t)
+		s := NewRedisTATStorage(rc)
+		mr.Set("key", "1700000000999999999")
+
+		// The stored time and the limit are the same float64, a numeric
+		// comparison in Lua would allow the request.
+		tat, allowed, err := s.TakeTAT(ctx, "key", now, 2, 1700000001000000000)
+		assert.NoError(t, err)
+		assert.False(t, allowed)
+		assert.Equal(t, int64(1700000000999999999), tat)
+
+		tat, allowed, err = s.TakeTAT(ctx, "key", now, 1, 1700000001000000000)
+		assert.NoError(t, err)
+		assert.True(t, allowed)
+		assert.Equal(t, int64(1700000000999999999), tat)
+
+		stored, _ := mr.Get("key")
+		assert.Equal(t, "1700000001000000000", stored)
+	})
+
+	t.Run("should expire the key at the new arrival time", func(t *testing.T) {
+		mr, rc := newTestClient(t)
+		s := NewRedisTATStorage(rc)
+
+		_, _, err := s.TakeTAT(ctx, "key", now, int64(2*time.Second), now+int64(time.Minute))
+		assert.NoError(t, err)
+		assert.Equal(t, 2*time.Second, mr.TTL("key"))
+
+		mr.FastForward(2 * time.Second)
+		assert.False(t, mr.Exists("key"))
+	})
+
+	t.Run("should handle small timestamps", func(t *testing.T) {
+		_, rc := newTestClient(t)
+		s := NewRedisTATStorage(rc)
+
+		tat, allowed, err := s.TakeTAT(ctx, "key", 100, 10, 115)
+		assert.NoError(t, err)
+		assert.True(t, allowed)
+		assert.Equal(t, int64(100), tat)
+
+		tat, allowed, err = s.TakeTAT(ctx, "key", 100, 10, 115)
+		assert.NoError(t, err)
+		assert.False(t, allowed)
+		assert.Equal(t, int64(110), tat)
+	})
+
+	t.Run("should return an error for a value that is not a timestamp", func(t *testing.T) {
+		mr, rc := newTestClient(t)
+		s := NewRedisTATStorage(rc)
+		mr.Set("key", "invalid")
+
+		_, _, err := s.TakeTAT(ctx, "key", now, 1, now+1)
+		assert.Error(t, err)
+	})
+}
@@ -3,12 +3,100 @@ package redis
 import (
 	"context"
 	"fmt"
+	"strconv"
 	"time"
 
 	"github.com/GeovaneCavalcante/rate-limit-api/pkg/ratelimit"
 	"github.com/go-redis/redis/v8"
 )
 
+// addWithinLimitScript trims the expired events, sums the weight of the
+// remaining ones and only adds the new events when they fit in the limit, all
+// in a single round trip so concurrent replicas can not overshoot the limit.
+// The events are given as score and member pairs after the first three
+// arguments. The weight of the window is kept in a second key, so a call only
+// reads the events leaving the window, and is summed again when it is missing.
+var addWithinLimitScript = redis.NewScript(`
+local key = KEYS[1]
+local weightKey = KEYS[2]
+local windowStart = ARGV[1]
+local limit = tonumber(ARGV[2])
+local ttl = tonumber(ARGV[3])
+
+local function weight(member)
+	local cost = string.match(member, "^event:[^:]+:[^:]+:(%d+)$")
+	if cost and tonumber(cost) >= 1 then
+		return tonumber(cost)
+	end
+	return 1
+end
+
+local count = redis.call("GET", weightKey)
+if count then
+	count = tonumber(count)
+	for _, member in ipairs(redis.call("ZRANGEBYSCORE", key, "-inf", windowStart)) do
+		count = count - weight(member)
+	end
+	redis.call("ZREMRANGEBYSCORE", key, "-inf", windowStart)
+else
+	redis.call("ZREMRANGEBYSCORE", key, "-inf", windowStart)
+	count = 0
+	for _, member in ipairs(redis.call("ZRANGE", key, 0, -1)) do
+		count = count + weight(member)
+	end
+end
+
+local n = 0
+for i = 5, #ARGV, 2 do
+	n = n + weight(ARGV[i])
+end
+
+local added = 0
+if count + n <= limit then
+	for i = 4, #ARGV, 2 do
+		if not redis.call("ZSCORE", key, ARGV[i + 1]) then
+			count = count + weight(ARGV[i + 1])
+		end
+		redis.call("ZADD", key, ARGV[i], ARGV[i + 1])
+	end
+	added = 1
+end
+
+if count <= 0 then
+	redis.call("DEL", key, weightKey)
+	return {0, added, "0", "0"}
+end
+
+redis.call("SET", weightKey, count)
+if ttl > 0 then
+	redis.call("PEXPIRE", key, ttl)
+	redis.call("PEXPIRE", weightKey, ttl)
+end
+
+local oldestScore = redis.call("ZRANGE", key, 0, 0, "WITHSCORES")[2]
+local retryScore = "0"
+if added == 0 then
+	local need = count + n - limit
+	local released = 0
+	local events = redis.call("ZRANGE", key, 0, need - 1, "WITHSCORES")
+	for i = 1, #events, 2 do
+		released = released + weight(events[i])
+		retryScore = events[i + 1]
+		if released >= need then
+			break
+		end
+	end
+end
+
+return {count, added, oldestScore, retryScore}
+`)
+
+// weightKey is the key holding the weight of the events of key, kept by
+// addWithinLimitScript.
+func weightKey(key string) string {
+	return "weight:" + key
+}
+
 type RedisEventStorage struct {
 	RedisClient *redis.Client
 }
@@ -55,7 +143,11 @@ func (res *RedisEventStorage) FindRangeWithScores(ctx context.Context, key strin
 
 func (res *RedisEventStorage) RemoveRangeByScore(ctx context.Context, key, min, max string) error {
 	min = parserMinScore(min)
-	err := res.RedisClient.ZRemRangeByScore(ctx, key, min, max).Err()
+	_, err := res.RedisClient.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.ZRemRangeByScore(ctx, key, min, max)
+		pipe.Del(ctx, weightKey(key))
+		return nil
+	})
 	if err != nil {
 		return err
 	}
@@ -70,7 +162,11 @@ func (res *RedisEventStorage) Add(ctx context.Context, key string, events ...*ra
 			Member: event.Value,
 		})
 	}
-	err := res.RedisClient.ZAdd(ctx, key, zEvents...).Err()
+	_, err := res.RedisClient.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.ZAdd(ctx, key, zEvents...)
+		pipe.Del(ctx, weightKey(key))
+		return nil
+	})
 	if err != nil {
 		return nil, err
 	}
@@ -78,9 +174,47 @@ func (res *RedisEventStorage) Add(ctx context.Context, key string, events ...*ra
 }
 
 func (res *RedisEventStorage) SetEventTLL(ctx context.Context, key string, ttl time.Duration) error {
-	err := res.RedisClient.Expire(ctx, key, ttl).Err()
+	err := res.RedisClient.PExpire(ctx, key, ttl).Err()
 	if err != nil {
 		return err
 	}
 	return nil
 }
+
+func (res *RedisEventStorage) AddWithinLimit(ctx context.Context, key string, events []*ratelimit.Event, windowStart float64, limit int64, ttl time.Duration) (*ratelimit.EventWindow, error) {
+	args := []interface{}{
+		strconv.FormatFloat(windowStart, 'f', -1, 64),
+		limit,
+		ttl.Milliseconds(),
+	}
+	for _, event := range events {
+		args = append(args, strconv.FormatFloat(event.Score, 'f', -1, 64), event.Value)
+	}
+
+	result, err := addWithinLimitScript.Run(ctx, res.RedisClient, []string{key, weightKey(key)}, args...).Slice()
+	if err != nil {
+		return nil, err
+	}
+	if len(result) != 4 {
+		return nil, fmt.Errorf("unexpected add within limit script result: %v", result)
+	}
+
+	count, _ := result[0].(int64)
+	added, _ := result[1].(int64)
+	oldestScore, err := strconv.ParseFloat(fmt.Sprint(result[2]), 64)
+	if err != nil {
+		return nil, err
+	}
+
+	retryScore, err := strconv.ParseFloat(fmt.Sprint(result[3]), 64)
+	if err != nil {
+		return nil, err
+	}
+
+	return &ratelimit.EventWindow{
+		Count:       count,
+		Added:       added == 1,
+		OldestScore: oldestScore,
+		RetryScore:  retryScore,
+	}, nil
+}
@@ -0,0 +1,50 @@
+package redis
+
+import (
+	"testing"
+	"time"
+
+	"github.com/GeovaneCavalcante/rate-limit-api/pkg/ratelimit"
+	"github.com/GeovaneCavalcante/rate-limit-api/pkg/ratelimit/storagetest"
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-redis/redis/v8"
+)
+
+func newTestClient(t *testing.T) (*miniredis.Miniredis, *redis.Client) {
+	mr := miniredis.RunT(t)
+	rc := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { rc.Close() })
+	return mr, rc
+}
+
+func TestRedisEventStorage(t *testing.T) {
+	var mr *miniredis.Miniredis
+
+	storagetest.RunEventStorageTests(t, storagetest.Backend{
+		NewStorage: func(t *testing.T) ratelimit.EventStorageInterface {
+			mr = miniredis.RunT(t)
+			rc := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+			t.Cleanup(func() { rc.Close() })
+			return NewRedisEventStorage(rc)
+		},
+		FastForward: func(t *testing.T, d time.Duration) {
+			mr.FastForward(d)
+		},
+	})
+}
+
+func TestRedisCounterStorage(t *testing.T) {
+	var mr *miniredis.Miniredis
+
+	storagetest.RunCounterStorageTests(t, storagetest.CounterBackend{
+		NewStorage: func(t *testing.T) ratelimit.CounterStorageInterface {
+			mr = miniredis.RunT(t)
+			rc := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+			t.Cleanup(func() { rc.Close() })
+			return NewRedisCounterStorage(rc)
+		},
+		FastForward: func(t *testing.T, d time.Duration) {
+			mr.FastForward(d)
+		},
+	})
+}
@@ -0,0 +1,79 @@
+package redis
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// takeTokenScript refills the bucket stored in a hash and takes cost tokens
+// when they are available. It mirrors ratelimit.TokenBucket.Take.
+var takeTokenScript = redis.NewScript(`
+local key = KEYS[1]
+local capacity = tonumber(ARGV[1])
+local refillRate = tonumber(ARGV[2])
+local now = tonumber(ARGV[3])
+local ttl = tonumber(ARGV[4])
+local cost = tonumber(ARGV[5])
+
+local bucket = redis.call("HMGET", key, "tokens", "updated_at")
+local tokens = tonumber(bucket[1])
+local updatedAt = tonumber(bucket[2])
+if tokens == nil or updatedAt == nil then
+	tokens = capacity
+	updatedAt = now
+end
+
+local elapsed = math.max(0, now - updatedAt)
+tokens = math.min(capacity, tokens + elapsed * refillRate)
+
+local allowed = 0
+if tokens >= cost then
+	tokens = tokens - cost
+	allowed = 1
+end
+
+redis.call("HSET", key, "tokens", tostring(tokens), "updated_at", tostring(now))
+if ttl > 0 then
+	redis.call("PEXPIRE", key, ttl)
+end
+
+return {tostring(tokens), allowed}
+`)
+
+type RedisTokenBucketStorage struct {
+	RedisClient *redis.Client
+}
+
+func NewRedisTokenBucketStorage(rc *redis.Client) *RedisTokenBucketStorage {
+	return &RedisTokenBucketStorage{
+		RedisClient: rc,
+	}
+}
+
+func (rts *RedisTokenBucketStorage) TakeToken(ctx context.Context, key string, capacity, cost int64, refillRate, now float64, ttl time.Duration) (float64, bool, error) {
+	result, err := takeTokenScript.Run(ctx, rts.RedisClient, []string{key},
+		capacity,
+		strconv.FormatFloat(refillRate, 'f', -1, 64),
+		strconv.FormatFloat(now, 'f', -1, 64),
+		ttl.Milliseconds(),
+		cost,
+	).Slice()
+	if err != nil {
+		return 0, false, err
+	}
+	if len(result) != 2 {
+		return 0, false, fmt.Errorf("unexpected take token script result: %v", result)
+	}
+
+	tokens, err := strconv.ParseFloat(fmt.Sprint(result[0]), 64)
+	if err != nil {
+		return 0, false, err
+	}
+	allowed, _ := result[1].(int64)
+
+	return tokens, allowed == 1, nil
+}
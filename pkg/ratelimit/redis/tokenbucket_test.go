@@ -0,0 +1,87 @@
+package redis
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRedisTokenBucketStorage(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("should start full and refill over time", func(t *testing.T) {
+		_, rc := newTestClient(t)
+		s := NewRedisTokenBucketStorage(rc)
+
+		tokens, allowed, err := s.TakeToken(ctx, "key", 2, 1, 1, 100, time.Minute)
+		assert.NoError(t, err)
+		assert.True(t, allowed)
+		assert.Equal(t, float64(1), tokens)
+
+		_, allowed, _ = s.TakeToken(ctx, "key", 2, 1, 1, 100, time.Minute)
+		assert.True(t, allowed)
+
+		tokens, allowed, err = s.TakeToken(ctx, "key", 2, 1, 1, 100.5, time.Minute)
+		assert.NoError(t, err)
+		assert.False(t, allowed)
+		assert.Equal(t, 0.5, tokens)
+
+		_, allowed, _ = s.TakeToken(ctx, "other", 2, 1, 1, 100.5, time.Minute)
+		assert.True(t, allowed)
+
+		tokens, allowed, _ = s.TakeToken(ctx, "key", 2, 1, 1, 101, time.Minute)
+		assert.True(t, allowed)
+		assert.Equal(t, float64(0), tokens)
+	})
+
+	t.Run("should not refill over the capacity", func(t *testing.T) {
+		_, rc := newTestClient(t)
+		s := NewRedisTokenBucketStorage(rc)
+
+		_, _, _ = s.TakeToken(ctx, "key", 2, 1, 1, 100, time.Minute)
+		tokens, allowed, err := s.TakeToken(ctx, "key", 2, 1, 1, 1000, time.Minute)
+		assert.NoError(t, err)
+		assert.True(t, allowed)
+		assert.Equal(t, float64(1), tokens)
+	})
+
+	t.Run("should take the cost at once", func(t *testing.T) {
+		_, rc := newTestClient(t)
+		s := NewRedisTokenBucketStorage(rc)
+
+		tokens, allowed, err := s.TakeToken(ctx, "key", 10, 7, 1, 100, time.Minute)
+		assert.NoError(t, err)
+		assert.True(t, allowed)
+		assert.Equal(t, float64(3), tokens)
+
+		tokens, allowed, err = s.TakeToken(ctx, "key", 10, 4, 1, 100, time.Minute)
+		assert.NoError(t, err)
+		assert.False(t, allowed)
+		assert.Equal(t, float64(3), tokens)
+
+		tokens, allowed, _ = s.TakeToken(ctx, "key", 10, 4, 1, 101, time.Minute)
+		assert.True(t, allowed)
+		assert.Equal(t, float64(0), tokens)
+	})
+
+	t.Run("should expire the bucket after the ttl", func(t *testing.T) {
+		mr, rc := newTestClient(t)
+		s := NewRedisTokenBucketStorage(rc)
+
+		_, _, err := s.TakeToken(ctx, "key", 2, 2, 0, 100, 2*time.Second)
+		assert.NoError(t, err)
+		assert.Equal(t, 2*time.Second, mr.TTL("key"))
+
+		_, allowed, _ := s.TakeToken(ctx, "key", 2, 1, 0, 100, 2*time.Second)
+		assert.False(t, allowed)
+
+		mr.FastForward(3 * time.Second)
+		assert.False(t, mr.Exists("key"))
+
+		tokens, allowed, _ := s.TakeToken(ctx, "key", 2, 1, 0, 100, 2*time.Second)
+		assert.True(t, allowed)
+		assert.Equal(t, float64(1), tokens)
+	})
+}
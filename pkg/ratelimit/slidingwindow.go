@@ -0,0 +1,148 @@
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"time"
+)
+
+type CounterStorageInterface interface {
+	GetCounters(ctx context.Context, keys ...string) ([]int64, error)
+	IncrementCounter(ctx context.Context, key string, amount int64, ttl time.Duration) (int64, error)
+}
+
+// CounterWindow is the state of the window counters read by an
+// IncrementWithinLimit call, before the increment.
+type CounterWindow struct {
+	Previous int64
+	Current  int64
+	Added    bool
+}
+
+// AtomicCounterStorage is an optional capability of a
+// CounterStorageInterface. IncrementWithinLimit must, as a single atomic
+// operation, read both counters, increment currentKey by amount only when
+// previous*previousWeight + current + amount fits in limit and then refresh
+// the TTL of currentKey.
+type AtomicCounterStorage interface {
+	CounterStorageInterface
+	IncrementWithinLimit(ctx context.Context, previousKey, currentKey string, previousWeight float64, amount, limit int64, ttl time.Duration) (*CounterWindow, error)
+}
+
+// SlidingWindowLimiter keeps one counter per fixed window and estimates the
+// rate of the sliding window by weighting the previous window counter with
+// the part of it that still overlaps the sliding window.
+type SlidingWindowLimiter struct {
+	CounterStorage CounterStorageInterface
+	Clock          Clock
+	Options
+}
+
+func NewSlidingWindowLimiter(cs CounterStorageInterface, ns string, max int64, inter time.Duration, opts ...Option) (*SlidingWindowLimiter, error) {
+	s := newSettings(opts)
+
+	return &SlidingWindowLimiter{
+		CounterStorage: cs,
+		Clock:          s.clock,
+		Options:        Options{NameSpace: ns, MaxInInterval: max, Interval: inter},
+	}, nil
+}
+
+func (sw *SlidingWindowLimiter) Limiter(ctx context.Context, key string, opt *Options) (bool, error) {
+	return limited(sw.Decide(ctx, key, opt))
+}
+
+func (sw *SlidingWindowLimiter) Decide(ctx context.Context, key string, opt *Options) (*Decision, error) {
+
+	timestamp := now(sw.Clock).UnixNano()
+
+	nameSpace := chooseString(sw.NameSpace, opt, func(o *Options) string { return o.NameSpace })
+	maxInInterval := chooseInt64(sw.MaxInInterval, opt, func(o *Options) int64 { return o.MaxInInterval })
+	interval := chooseDuration(sw.Interval, opt, func(o *Options) time.Duration { return o.Interval })
+	cost := requestCost(opt)
+
+	if interval <= 0 {
+		return nil, fmt.Errorf("invalid sliding window interval: %s", interval)
+	}
+	window := timestamp / int64(interval)
+	windowStart := window * int64(interval)
+	elapsed := float64(timestamp-windowStart) / float64(interval)
+
+	currentKey := fmt.Sprintf("%s:%s:%d", nameSpace, key, window)
+	previousKey := fmt.Sprintf("%s:%s:%d", nameSpace, key, window-1)
+
+	decision := &Decision{
+		Limit:     maxInInterval,
+		Window:    interval,
+		ResetAt:   time.Unix(0, windowStart+int64(interval)),
+		NameSpace: nameSpace,
+		Key:       key,
+	}
+
+	if exceedsLimit(cost, maxInInterval) {
+		decision.RetryAfter = interval
+		return decision, nil
+	}
+
+	if as, ok := sw.CounterStorage.(AtomicCounterStorage); ok {
+		window, err := as.IncrementWithinLimit(ctx, previousKey, currentKey, 1-elapsed, cost, maxInInterval, 2*interval)
+		if err != nil {
+			return nil, fmt.Errorf("error when incrementing window counter within limit: %w", err)
+		}
+		return slidingWindowDecision(decision, window, elapsed, cost, windowStart, timestamp, int64(interval)), nil
+	}
+
+	counters, err := sw.CounterStorage.GetCounters(ctx, previousKey, currentKey)
+	if err != nil {
+		return nil, fmt.Errorf("error when getting window counters: %w", err)
+	}
+	if len(counters) != 2 {
+		return nil, fmt.Errorf("error when getting window counters: expected 2 counters, got %d", len(counters))
+	}
+	previous, current := counters[0], counters[1]
+
+	estimated := float64(previous)*(1-elapsed) + float64(current)
+	if estimated+float64(cost) > float64(maxInInterval) {
+		return slidingWindowDecision(decision, &CounterWindow{Previous: previous, Current: current}, elapsed, cost, windowStart, timestamp, int64(interval)), nil
+	}
+
+	_, err = sw.CounterStorage.IncrementCounter(ctx, currentKey, cost, 2*interval)
+	if err != nil {
+		return nil, fmt.Errorf("error when incrementing window counter: %w", err)
+	}
+
+	return slidingWindowDecision(decision, &CounterWindow{Previous: previous, Current: current, Added: true}, elapsed, cost, windowStart, timestamp, int64(interval)), nil
+}
+
+// slidingWindowDecision fills the decision from the counters read before the
+// increment.
+func slidingWindowDecision(decision *Decision, window *CounterWindow, elapsed float64, cost, windowStart, timestamp, interval int64) *Decision {
+	if !window.Added {
+		decision.RetryAfter = time.Duration(slidingWindowAllowedAt(window.Previous, window.Current, decision.Limit, cost, windowStart, interval) - timestamp)
+		decision.Remaining = 0
+		return decision
+	}
+
+	estimated := float64(window.Previous)*(1-elapsed) + float64(window.Current)
+	decision.Allowed = true
+	decision.Remaining = max(0, decision.Limit-int64(math.Ceil(estimated+float64(cost))))
+	return decision
+}
+
+// slidingWindowAllowedAt returns the Unix nanoseconds at which the weighted
+// previous window decays enough for a request of the given cost to fit in the
+// limit.
+func slidingWindowAllowedAt(previous, current, maxInInterval, cost, windowStart, interval int64) int64 {
+	if current+cost <= maxInInterval && previous > 0 {
+		elapsed := 1 - float64(maxInInterval-current-cost)/float64(previous)
+		return windowStart + int64(math.Ceil(elapsed*float64(interval)))
+	}
+
+	nextWindowStart := windowStart + interval
+	if current == 0 || maxInInterval <= 0 {
+		return nextWindowStart + interval
+	}
+	elapsed := math.Max(0, 1-float64(maxInInterval-cost)/float64(current))
+	return nextWindowStart + int64(math.Ceil(elapsed*float64(interval)))
+}
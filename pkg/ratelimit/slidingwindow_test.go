@@ -0,0 +1,220 @@
+package ratelimit_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/GeovaneCavalcante/rate-limit-api/pkg/ratelimit"
+	"github.com/GeovaneCavalcante/rate-limit-api/pkg/ratelimit/clocktest"
+	"github.com/GeovaneCavalcante/rate-limit-api/pkg/ratelimit/memory"
+	mock_storage "github.com/GeovaneCavalcante/rate-limit-api/pkg/ratelimit/mock"
+	ratelimitredis "github.com/GeovaneCavalcante/rate-limit-api/pkg/ratelimit/redis"
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-redis/redis/v8"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+	"go.uber.org/mock/gomock"
+)
+
+type SlidingWindowTestSuite struct {
+	suite.Suite
+	CounterStorageMock *mock_storage.MockCounterStorageInterface
+}
+
+func (suite *SlidingWindowTestSuite) SetupTest() {
+	ctrl := gomock.NewController(suite.T())
+	suite.CounterStorageMock = mock_storage.NewMockCounterStorageInterface(ctrl)
+}
+
+func (suite *SlidingWindowTestSuite) TestLimiter() {
+	suite.Run("should return false and increment the current window when below the limit", func() {
+		suite.CounterStorageMock.EXPECT().GetCounters(gomock.Any(), gomock.Any(), gomock.Any()).Return([]int64{0, 1}, nil)
+		suite.CounterStorageMock.EXPECT().IncrementCounter(gomock.Any(), gomock.Any(), int64(1), 120*time.Second).Return(int64(2), nil)
+
+		sw, err := ratelimit.NewSlidingWindowLimiter(suite.CounterStorageMock, "test", 2, 60*time.Second)
+		if err != nil {
+			suite.FailNow(err.Error())
+		}
+
+		value, err := sw.Limiter(context.Background(), "key", nil)
+		assert.NoError(suite.T(), err)
+		assert.False(suite.T(), value)
+	})
+
+	suite.Run("should return true without incrementing when the limit is reached", func() {
+		suite.CounterStorageMock.EXPECT().GetCounters(gomock.Any(), gomock.Any(), gomock.Any()).Return([]int64{0, 2}, nil)
+
+		sw, err := ratelimit.NewSlidingWindowLimiter(suite.CounterStorageMock, "test", 2, 60*time.Second)
+		if err != nil {
+			suite.FailNow(err.Error())
+		}
+
+		value, err := sw.Limiter(context.Background(), "key", nil)
+		assert.NoError(suite.T(), err)
+		assert.True(suite.T(), value)
+	})
+
+	suite.Run("should use the previous and current window keys", func() {
+		suite.CounterStorageMock.EXPECT().GetCounters(gomock.Any(), gomock.Any(), gomock.Any()).
+			DoAndReturn(func(_ context.Context, keys ...string) ([]int64, error) {
+				assert.Len(suite.T(), keys, 2)
+				assert.Regexp(suite.T(), `^token:key:\d+$`, keys[0])
+				assert.Regexp(suite.T(), `^token:key:\d+$`, keys[1])
+				assert.NotEqual(suite.T(), keys[0], keys[1])
+				return []int64{0, 0}, nil
+			})
+		suite.CounterStorageMock.EXPECT().IncrementCounter(gomock.Any(), gomock.Any(), int64(1), 60*time.Second).Return(int64(1), nil)
+
+		sw, err := ratelimit.NewSlidingWindowLimiter(suite.CounterStorageMock, "test", 2, 60*time.Second)
+		if err != nil {
+			suite.FailNow(err.Error())
+		}
+
+		value, err := sw.Limiter(context.Background(), "key", &ratelimit.Options{
+			NameSpace:     "token",
+			MaxInInterval: 5,
+			Interval:      30 * time.Second,
+		})
+		assert.NoError(suite.T(), err)
+		assert.False(suite.T(), value)
+	})
+
+	suite.Run("should return an error when the interval is invalid", func() {
+		sw, err := ratelimit.NewSlidingWindowLimiter(suite.CounterStorageMock, "test", 2, 0)
+		if err != nil {
+			suite.FailNow(err.Error())
+		}
+
+		value, err := sw.Limiter(context.Background(), "key", nil)
+		assert.Equal(suite.T(), "invalid sliding window interval: 0s", err.Error())
+		assert.False(suite.T(), value)
+	})
+
+	suite.Run("should return an error when getting the counters fails", func() {
+		suite.CounterStorageMock.EXPECT().GetCounters(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil, errors.New("error"))
+
+		sw, err := ratelimit.NewSlidingWindowLimiter(suite.CounterStorageMock, "test", 2, 60*time.Second)
+		if err != nil {
+			suite.FailNow(err.Error())
+		}
+
+		value, err := sw.Limiter(context.Background(), "key", nil)
+		assert.Equal(suite.T(), "error when getting window counters: error", err.Error())
+		assert.False(suite.T(), value)
+	})
+
+	suite.Run("should return an error when incrementing the counter fails", func() {
+		suite.CounterStorageMock.EXPECT().GetCounters(gomock.Any(), gomock.Any(), gomock.Any()).Return([]int64{0, 0}, nil)
+		suite.CounterStorageMock.EXPECT().IncrementCounter(gomock.Any(), gomock.Any(), int64(1), gomock.Any()).Return(int64(0), errors.New("error"))
+
+		sw, err := ratelimit.NewSlidingWindowLimiter(suite.CounterStorageMock, "test", 2, 60*time.Second)
+		if err != nil {
+			suite.FailNow(err.Error())
+		}
+
+		value, err := sw.Limiter(context.Background(), "key", nil)
+		assert.Equal(suite.T(), "error when incrementing window counter: error", err.Error())
+		assert.False(suite.T(), value)
+	})
+}
+
+func (suite *SlidingWindowTestSuite) TestDecide() {
+	suite.Run("should return the remaining requests when below the limit", func() {
+		suite.CounterStorageMock.EXPECT().GetCounters(gomock.Any(), gomock.Any(), gomock.Any()).Return([]int64{0, 1}, nil)
+		suite.CounterStorageMock.EXPECT().IncrementCounter(gomock.Any(), gomock.Any(), int64(1), gomock.Any()).Return(int64(2), nil)
+
+		sw, err := ratelimit.NewSlidingWindowLimiter(suite.CounterStorageMock, "test", 5, 60*time.Second)
+		if err != nil {
+			suite.FailNow(err.Error())
+		}
+
+		decision, err := sw.Decide(context.Background(), "key", nil)
+		assert.NoError(suite.T(), err)
+		assert.True(suite.T(), decision.Allowed)
+		assert.Equal(suite.T(), int64(5), decision.Limit)
+		assert.Equal(suite.T(), int64(3), decision.Remaining)
+		assert.Equal(suite.T(), time.Duration(0), decision.RetryAfter)
+	})
+
+	suite.Run("should wait for the current window to decay when it is full", func() {
+		suite.CounterStorageMock.EXPECT().GetCounters(gomock.Any(), gomock.Any(), gomock.Any()).Return([]int64{0, 5}, nil)
+
+		sw, err := ratelimit.NewSlidingWindowLimiter(suite.CounterStorageMock, "test", 5, 60*time.Second)
+		if err != nil {
+			suite.FailNow(err.Error())
+		}
+
+		decision, err := sw.Decide(context.Background(), "key", nil)
+		assert.NoError(suite.T(), err)
+		assert.False(suite.T(), decision.Allowed)
+		assert.Equal(suite.T(), int64(0), decision.Remaining)
+		assert.WithinDuration(suite.T(), decision.ResetAt.Add(12*time.Second), time.Now().Add(decision.RetryAfter), 100*time.Millisecond)
+	})
+}
+
+func (suite *SlidingWindowTestSuite) TestWeightedPreviousWindow() {
+	suite.Run("should weight the previous window by its overlap", func() {
+		clock := clocktest.NewFakeClock(time.Unix(1699999980, 0))
+		sw, err := ratelimit.NewSlidingWindowLimiter(memory.NewMemoryCounterStorage(0, memory.WithClock(clock)), "test", 4, 60*time.Second, ratelimit.WithClock(clock))
+		if err != nil {
+			suite.FailNow(err.Error())
+		}
+
+		for i := 0; i < 4; i++ {
+			value, err := sw.Limiter(context.Background(), "key", nil)
+			assert.NoError(suite.T(), err)
+			assert.False(suite.T(), value)
+		}
+
+		// 1699999980 is 0s into its window, 30s later the previous window
+		// still weights 4 * 0.5 = 2 requests.
+		clock.Advance(90 * time.Second)
+		for i := 0; i < 2; i++ {
+			value, err := sw.Limiter(context.Background(), "key", nil)
+			assert.NoError(suite.T(), err)
+			assert.False(suite.T(), value)
+		}
+
+		decision, err := sw.Decide(context.Background(), "key", nil)
+		assert.NoError(suite.T(), err)
+		assert.False(suite.T(), decision.Allowed)
+		assert.Equal(suite.T(), 15*time.Second, decision.RetryAfter)
+	})
+}
+
+func (suite *SlidingWindowTestSuite) TestConcurrency() {
+	suite.Run("should never allow more than the limit under concurrency", func() {
+		mr := miniredis.RunT(suite.T())
+		rc := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+		defer rc.Close()
+
+		sw, err := ratelimit.NewSlidingWindowLimiter(ratelimitredis.NewRedisCounterStorage(rc), "test", 10, time.Minute)
+		if err != nil {
+			suite.FailNow(err.Error())
+		}
+
+		var allowed atomic.Int64
+		var wg sync.WaitGroup
+		for i := 0; i < 20; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				decision, err := sw.Decide(context.Background(), "key", nil)
+				if assert.NoError(suite.T(), err) && decision.Allowed {
+					allowed.Add(1)
+				}
+			}()
+		}
+		wg.Wait()
+
+		assert.Equal(suite.T(), int64(10), allowed.Load())
+	})
+}
+
+func TestSlidingWindowSuite(t *testing.T) {
+	suite.Run(t, new(SlidingWindowTestSuite))
+}
@@ -0,0 +1,154 @@
+package storagetest
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/GeovaneCavalcante/rate-limit-api/pkg/ratelimit"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// CounterBackend describes the counter storage under test, with the same
+// contract as Backend.
+type CounterBackend struct {
+	NewStorage  func(t *testing.T) ratelimit.CounterStorageInterface
+	FastForward func(t *testing.T, d time.Duration)
+}
+
+// RunCounterStorageTests runs the conformance suite against the counter
+// backend. The IncrementWithinLimit tests only run when the storage
+// implements ratelimit.AtomicCounterStorage.
+func RunCounterStorageTests(t *testing.T, b CounterBackend) {
+	t.Run("IncrementCounter", func(t *testing.T) { testIncrementCounter(t, b) })
+	t.Run("IncrementWithinLimit", func(t *testing.T) { testIncrementWithinLimit(t, b) })
+}
+
+func testIncrementCounter(t *testing.T, b CounterBackend) {
+	ctx := context.Background()
+
+	t.Run("missing keys are zero", func(t *testing.T) {
+		s := b.NewStorage(t)
+		counters, err := s.GetCounters(ctx, "a", "b")
+		assert.NoError(t, err)
+		assert.Equal(t, []int64{0, 0}, counters)
+	})
+
+	t.Run("adds the amount", func(t *testing.T) {
+		s := b.NewStorage(t)
+
+		value, err := s.IncrementCounter(ctx, "key", 1, time.Minute)
+		assert.NoError(t, err)
+		assert.Equal(t, int64(1), value)
+
+		value, err = s.IncrementCounter(ctx, "key", 3, time.Minute)
+		assert.NoError(t, err)
+		assert.Equal(t, int64(4), value)
+	})
+
+	t.Run("expires the counter", func(t *testing.T) {
+		s := b.NewStorage(t)
+		_, err := s.IncrementCounter(ctx, "key", 1, time.Second)
+		require.NoError(t, err)
+
+		fastForward(t, Backend{FastForward: b.FastForward}, 2*time.Second)
+
+		counters, err := s.GetCounters(ctx, "key")
+		assert.NoError(t, err)
+		assert.Equal(t, []int64{0}, counters)
+	})
+}
+
+func testIncrementWithinLimit(t *testing.T, b CounterBackend) {
+	if _, ok := b.NewStorage(t).(ratelimit.AtomicCounterStorage); !ok {
+		t.Skip("storage does not implement ratelimit.AtomicCounterStorage")
+	}
+	ctx := context.Background()
+
+	t.Run("increments the current counter within the limit", func(t *testing.T) {
+		s := b.NewStorage(t).(ratelimit.AtomicCounterStorage)
+		_, err := s.IncrementCounter(ctx, "previous", 4, time.Minute)
+		require.NoError(t, err)
+
+		window, err := s.IncrementWithinLimit(ctx, "previous", "current", 0.5, 3, 10, time.Minute)
+		assert.NoError(t, err)
+		assert.Equal(t, &ratelimit.CounterWindow{Previous: 4, Current: 0, Added: true}, window)
+
+		counters, err := s.GetCounters(ctx, "previous", "current")
+		assert.NoError(t, err)
+		assert.Equal(t, []int64{4, 3}, counters)
+	})
+
+	t.Run("weights the previous counter", func(t *testing.T) {
+		s := b.NewStorage(t).(ratelimit.AtomicCounterStorage)
+		_, err := s.IncrementCounter(ctx, "previous", 10, time.Minute)
+		require.NoError(t, err)
+
+		window, err := s.IncrementWithinLimit(ctx, "previous", "current", 0.25, 8, 10, time.Minute)
+		assert.NoError(t, err)
+		assert.False(t, window.Added)
+
+		window, err = s.IncrementWithinLimit(ctx, "previous", "current", 0.25, 7, 10, time.Minute)
+		assert.NoError(t, err)
+		assert.True(t, window.Added)
+	})
+
+	t.Run("does not increment over the limit", func(t *testing.T) {
+		s := b.NewStorage(t).(ratelimit.AtomicCounterStorage)
+		_, err := s.IncrementCounter(ctx, "current", 9, time.Minute)
+		require.NoError(t, err)
+
+		window, err := s.IncrementWithinLimit(ctx, "previous", "current", 1, 2, 10, time.Minute)
+		assert.NoError(t, err)
+		assert.Equal(t, &ratelimit.CounterWindow{Previous: 0, Current: 9, Added: false}, window)
+
+		counters, err := s.GetCounters(ctx, "current")
+		assert.NoError(t, err)
+		assert.Equal(t, []int64{9}, counters)
+	})
+
+	t.Run("sets the ttl of the current counter", func(t *testing.T) {
+		s := b.NewStorage(t).(ratelimit.AtomicCounterStorage)
+		_, err := s.IncrementWithinLimit(ctx, "previous", "current", 1, 1, 10, time.Second)
+		require.NoError(t, err)
+
+		fastForward(t, Backend{FastForward: b.FastForward}, 2*time.Second)
+
+		counters, err := s.GetCounters(ctx, "current")
+		assert.NoError(t, err)
+		assert.Equal(t, []int64{0}, counters)
+	})
+
+	t.Run("never overshoots the limit under concurrency", func(t *testing.T) {
+		const workers, limit = 50, 10
+		s := b.NewStorage(t).(ratelimit.AtomicCounterStorage)
+
+		var mu sync.Mutex
+		var added int
+
+		var wg sync.WaitGroup
+		for i := 0; i < workers; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				window, err := s.IncrementWithinLimit(ctx, "previous", "current", 1, 1, limit, time.Minute)
+				if !assert.NoError(t, err) {
+					return
+				}
+				if window.Added {
+					mu.Lock()
+					added++
+					mu.Unlock()
+				}
+			}()
+		}
+		wg.Wait()
+
+		assert.Equal(t, limit, added)
+		counters, err := s.GetCounters(ctx, "current")
+		assert.NoError(t, err)
+		assert.Equal(t, []int64{limit}, counters)
+	})
+}
@@ -0,0 +1,401 @@
+// Package storagetest provides conformance suites for the implementations of
+// ratelimit.EventStorageInterface and ratelimit.CounterStorageInterface,
+// checking they behave like the Redis sorted sets and counters the ratelimit
+// package was designed for.
+package storagetest
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/GeovaneCavalcante/rate-limit-api/pkg/ratelimit"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Backend describes the storage under test. NewStorage must return an empty
+// storage on each call. FastForward must make the storage observe d elapsed,
+// when it is nil the suite sleeps instead.
+type Backend struct {
+	NewStorage  func(t *testing.T) ratelimit.EventStorageInterface
+	FastForward func(t *testing.T, d time.Duration)
+}
+
+// RunEventStorageTests runs the conformance suite against the backend. The
+// AddWithinLimit tests only run when the storage implements
+// ratelimit.AtomicEventStorage.
+func RunEventStorageTests(t *testing.T, b Backend) {
+	t.Run("CountRange", func(t *testing.T) { testCountRange(t, b) })
+	t.Run("FindRangeWithScores", func(t *testing.T) { testFindRangeWithScores(t, b) })
+	t.Run("RemoveRangeByScore", func(t *testing.T) { testRemoveRangeByScore(t, b) })
+	t.Run("Add", func(t *testing.T) { testAdd(t, b) })
+	t.Run("SetEventTLL", func(t *testing.T) { testSetEventTLL(t, b) })
+	t.Run("Concurrency", func(t *testing.T) { testConcurrency(t, b) })
+	t.Run("AddWithinLimit", func(t *testing.T) { testAddWithinLimit(t, b) })
+}
+
+func event(score float64, value string) *ratelimit.Event {
+	return &ratelimit.Event{Score: score, Value: value}
+}
+
+func seed(t *testing.T, s ratelimit.EventStorageInterface, key string, scores ...float64) {
+	t.Helper()
+	for i, score := range scores {
+		_, err := s.Add(context.Background(), key, event(score, fmt.Sprintf("event:%d", i)))
+		require.NoError(t, err)
+	}
+}
+
+func values(events []*ratelimit.Event) []string {
+	var vs []string
+	for _, e := range events {
+		vs = append(vs, e.Value)
+	}
+	return vs
+}
+
+func scores(events []*ratelimit.Event) []float64 {
+	var ss []float64
+	for _, e := range events {
+		ss = append(ss, e.Score)
+	}
+	return ss
+}
+
+func testCountRange(t *testing.T, b Backend) {
+	ctx := context.Background()
+	s := b.NewStorage(t)
+	seed(t, s, "key", 1, 2, 3, 4)
+
+	tests := []struct {
+		name     string
+		min, max string
+		want     int64
+	}{
+		{"min sentinel", "min", "2", 2},
+		{"inclusive bounds", "2", "3", 2},
+		{"whole range", "min", "+inf", 4},
+		{"below the range", "min", "0", 0},
+		{"above the range", "5", "+inf", 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			count, err := s.CountRange(ctx, "key", tt.min, tt.max)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, count)
+		})
+	}
+
+	t.Run("missing key", func(t *testing.T) {
+		count, err := s.CountRange(ctx, "missing", "min", "+inf")
+		assert.NoError(t, err)
+		assert.Equal(t, int64(0), count)
+	})
+}
+
+func testFindRangeWithScores(t *testing.T, b Backend) {
+	ctx := context.Background()
+	s := b.NewStorage(t)
+
+	_, err := s.Add(ctx, "key", event(3, "c"), event(1, "a"), event(2, "b2"), event(2, "b1"))
+	require.NoError(t, err)
+
+	t.Run("orders by score then value", func(t *testing.T) {
+		events, err := s.FindRangeWithScores(ctx, "key", 0, -1)
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"a", "b1", "b2", "c"}, values(events))
+		assert.Equal(t, []float64{1, 2, 2, 3}, scores(events))
+	})
+
+	t.Run("oldest event", func(t *testing.T) {
+		events, err := s.FindRangeWithScores(ctx, "key", 0, 0)
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"a"}, values(events))
+	})
+
+	t.Run("negative indexes", func(t *testing.T) {
+		events, err := s.FindRangeWithScores(ctx, "key", -2, -1)
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"b2", "c"}, values(events))
+	})
+
+	t.Run("out of range", func(t *testing.T) {
+		events, err := s.FindRangeWithScores(ctx, "key", 10, 20)
+		assert.NoError(t, err)
+		assert.Empty(t, events)
+	})
+
+	t.Run("missing key", func(t *testing.T) {
+		events, err := s.FindRangeWithScores(ctx, "missing", 0, 0)
+		assert.NoError(t, err)
+		assert.Empty(t, events)
+	})
+}
+
+func testRemoveRangeByScore(t *testing.T, b Backend) {
+	ctx := context.Background()
+
+	tests := []struct {
+		name     string
+		min, max string
+		want     []float64
+	}{
+		{"min sentinel", "min", "2", []float64{3, 4}},
+		{"inclusive bounds", "2", "3", []float64{1, 4}},
+		{"nothing in range", "5", "6", []float64{1, 2, 3, 4}},
+		{"everything", "min", "+inf", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := b.NewStorage(t)
+			seed(t, s, "key", 1, 2, 3, 4)
+
+			err := s.RemoveRangeByScore(ctx, "key", tt.min, tt.max)
+			assert.NoError(t, err)
+
+			events, err := s.FindRangeWithScores(ctx, "key", 0, -1)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, scores(events))
+		})
+	}
+
+	t.Run("missing key", func(t *testing.T) {
+		s := b.NewStorage(t)
+		assert.NoError(t, s.RemoveRangeByScore(ctx, "missing", "min", "+inf"))
+	})
+}
+
+func testAdd(t *testing.T, b Backend) {
+	ctx := context.Background()
+
+	t.Run("adds multiple events", func(t *testing.T) {
+		s := b.NewStorage(t)
+		added := []*ratelimit.Event{event(1, "a"), event(2, "b"), event(3, "c")}
+
+		events, err := s.Add(ctx, "key", added...)
+		assert.NoError(t, err)
+		assert.Equal(t, added, events)
+
+		count, err := s.CountRange(ctx, "key", "min", "+inf")
+		assert.NoError(t, err)
+		assert.Equal(t, int64(3), count)
+	})
+
+	t.Run("updates the score of an existing value", func(t *testing.T) {
+		s := b.NewStorage(t)
+
+		_, err := s.Add(ctx, "key", event(1, "a"), event(2, "b"))
+		require.NoError(t, err)
+		_, err = s.Add(ctx, "key", event(3, "a"))
+		require.NoError(t, err)
+
+		events, err := s.FindRangeWithScores(ctx, "key", 0, -1)
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"b", "a"}, values(events))
+		assert.Equal(t, []float64{2, 3}, scores(events))
+	})
+
+	t.Run("keeps the keys apart", func(t *testing.T) {
+		s := b.NewStorage(t)
+		seed(t, s, "a", 1, 2)
+		seed(t, s, "b", 1)
+
+		count, err := s.CountRange(ctx, "b", "min", "+inf")
+		assert.NoError(t, err)
+		assert.Equal(t, int64(1), count)
+	})
+}
+
+func testSetEventTLL(t *testing.T, b Backend) {
+	ctx := context.Background()
+	s := b.NewStorage(t)
+	seed(t, s, "expiring", 1, 2)
+	seed(t, s, "kept", 1)
+
+	require.NoError(t, s.SetEventTLL(ctx, "expiring", 50*time.Millisecond))
+	require.NoError(t, s.SetEventTLL(ctx, "kept", time.Hour))
+
+	count, err := s.CountRange(ctx, "expiring", "min", "+inf")
+	assert.NoError(t, err)
+	assert.Equal(t, int64(2), count)
+
+	fastForward(t, b, 100*time.Millisecond)
+
+	count, err = s.CountRange(ctx, "expiring", "min", "+inf")
+	assert.NoError(t, err)
+	assert.Equal(t, int64(0), count)
+
+	count, err = s.CountRange(ctx, "kept", "min", "+inf")
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), count)
+
+	assert.NoError(t, s.SetEventTLL(ctx, "missing", time.Hour))
+}
+
+func testConcurrency(t *testing.T, b Backend) {
+	const workers = 50
+	ctx := context.Background()
+	s := b.NewStorage(t)
+
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			_, err := s.Add(ctx, "key", event(float64(i), fmt.Sprintf("event:%d", i)))
+			assert.NoError(t, err)
+			_, err = s.CountRange(ctx, "key", "min", "+inf")
+			assert.NoError(t, err)
+		}(i)
+	}
+	wg.Wait()
+
+	count, err := s.CountRange(ctx, "key", "min", "+inf")
+	assert.NoError(t, err)
+	assert.Equal(t, int64(workers), count)
+}
+
+func testAddWithinLimit(t *testing.T, b Backend) {
+	ctx := context.Background()
+
+	if _, ok := b.NewStorage(t).(ratelimit.AtomicEventStorage); !ok {
+		t.Skip("the storage does not implement ratelimit.AtomicEventStorage")
+	}
+
+	t.Run("adds while below the limit and trims the expired events", func(t *testing.T) {
+		s := b.NewStorage(t).(ratelimit.AtomicEventStorage)
+
+		window, err := s.AddWithinLimit(ctx, "key", []*ratelimit.Event{event(10, "a")}, 0, 2, time.Minute)
+		assert.NoError(t, err)
+		assert.Equal(t, &ratelimit.EventWindow{Count: 1, Added: true, OldestScore: 10}, window)
+
+		window, err = s.AddWithinLimit(ctx, "key", []*ratelimit.Event{event(11, "b")}, 1, 2, time.Minute)
+		assert.NoError(t, err)
+		assert.Equal(t, &ratelimit.EventWindow{Count: 2, Added: true, OldestScore: 10}, window)
+
+		window, err = s.AddWithinLimit(ctx, "key", []*ratelimit.Event{event(12, "c")}, 2, 2, time.Minute)
+		assert.NoError(t, err)
+		assert.Equal(t, &ratelimit.EventWindow{Count: 2, Added: false, OldestScore: 10, RetryScore: 10}, window)
+
+		window, err = s.AddWithinLimit(ctx, "key", []*ratelimit.Event{event(13, "d")}, 10, 2, time.Minute)
+		assert.NoError(t, err)
+		assert.Equal(t, &ratelimit.EventWindow{Count: 2, Added: true, OldestScore: 11}, window)
+	})
+
+	t.Run("adds several events only when all of them fit", func(t *testing.T) {
+		s := b.NewStorage(t).(ratelimit.AtomicEventStorage)
+
+		window, err := s.AddWithinLimit(ctx, "key", []*ratelimit.Event{event(10, "a"), event(11, "b"), event(12, "c")}, 0, 4, time.Minute)
+		assert.NoError(t, err)
+		assert.Equal(t, &ratelimit.EventWindow{Count: 3, Added: true, OldestScore: 10}, window)
+
+		window, err = s.AddWithinLimit(ctx, "key", []*ratelimit.Event{event(13, "d"), event(13, "e"), event(13, "f")}, 0, 4, time.Minute)
+		assert.NoError(t, err)
+		assert.Equal(t, &ratelimit.EventWindow{Count: 3, Added: false, OldestScore: 10, RetryScore: 11}, window)
+
+		count, err := s.CountRange(ctx, "key", "min", "+inf")
+		assert.NoError(t, err)
+		assert.Equal(t, int64(3), count)
+	})
+
+	t.Run("sums the weight of the events", func(t *testing.T) {
+		s := b.NewStorage(t).(ratelimit.AtomicEventStorage)
+
+		window, err := s.AddWithinLimit(ctx, "key", []*ratelimit.Event{event(10, "event:a:10:3")}, 0, 10, time.Minute)
+		assert.NoError(t, err)
+		assert.Equal(t, &ratelimit.EventWindow{Count: 3, Added: true, OldestScore: 10}, window)
+
+		window, err = s.AddWithinLimit(ctx, "key", []*ratelimit.Event{event(11, "event:b:11"), event(12, "event:c:12:4")}, 0, 10, time.Minute)
+		assert.NoError(t, err)
+		assert.Equal(t, &ratelimit.EventWindow{Count: 8, Added: true, OldestScore: 10}, window)
+
+		window, err = s.AddWithinLimit(ctx, "key", []*ratelimit.Event{event(13, "event:d:13:6")}, 0, 10, time.Minute)
+		assert.NoError(t, err)
+		assert.Equal(t, &ratelimit.EventWindow{Count: 8, Added: false, OldestScore: 10, RetryScore: 11}, window)
+
+		window, err = s.AddWithinLimit(ctx, "key", []*ratelimit.Event{event(13, "event:d:13:6")}, 10, 10, time.Minute)
+		assert.NoError(t, err)
+		assert.Equal(t, &ratelimit.EventWindow{Count: 5, Added: false, OldestScore: 11, RetryScore: 11}, window)
+
+		window, err = s.AddWithinLimit(ctx, "key", []*ratelimit.Event{event(13, "event:d:13:6")}, 11, 10, time.Minute)
+		assert.NoError(t, err)
+		assert.Equal(t, &ratelimit.EventWindow{Count: 10, Added: true, OldestScore: 12}, window)
+
+		count, err := s.CountRange(ctx, "key", "min", "+inf")
+		assert.NoError(t, err)
+		assert.Equal(t, int64(2), count)
+	})
+
+	t.Run("sums the weight of the events added without a limit", func(t *testing.T) {
+		s := b.NewStorage(t).(ratelimit.AtomicEventStorage)
+
+		_, err := s.Add(ctx, "key", event(10, "event:a:10:5"), event(11, "event:b:11"))
+		require.NoError(t, err)
+
+		window, err := s.AddWithinLimit(ctx, "key", []*ratelimit.Event{event(12, "event:c:12:4")}, 0, 10, time.Minute)
+		assert.NoError(t, err)
+		assert.Equal(t, &ratelimit.EventWindow{Count: 10, Added: true, OldestScore: 10}, window)
+
+		require.NoError(t, s.RemoveRangeByScore(ctx, "key", "min", "10"))
+
+		window, err = s.AddWithinLimit(ctx, "key", []*ratelimit.Event{event(13, "event:d:13:5")}, 0, 10, time.Minute)
+		assert.NoError(t, err)
+		assert.Equal(t, &ratelimit.EventWindow{Count: 10, Added: true, OldestScore: 11}, window)
+	})
+
+	t.Run("sets the ttl", func(t *testing.T) {
+		s := b.NewStorage(t).(ratelimit.AtomicEventStorage)
+
+		_, err := s.AddWithinLimit(ctx, "key", []*ratelimit.Event{event(1, "a")}, 0, 2, 50*time.Millisecond)
+		require.NoError(t, err)
+
+		fastForward(t, b, 100*time.Millisecond)
+
+		count, err := s.CountRange(ctx, "key", "min", "+inf")
+		assert.NoError(t, err)
+		assert.Equal(t, int64(0), count)
+	})
+
+	t.Run("never overshoots the limit under concurrency", func(t *testing.T) {
+		const workers, limit = 50, 10
+		s := b.NewStorage(t).(ratelimit.AtomicEventStorage)
+
+		var mu sync.Mutex
+		var added int
+
+		var wg sync.WaitGroup
+		for i := 0; i < workers; i++ {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				window, err := s.AddWithinLimit(ctx, "key", []*ratelimit.Event{event(100, fmt.Sprintf("event:%d", i))}, 0, limit, time.Minute)
+				if !assert.NoError(t, err) {
+					return
+				}
+				if window.Added {
+					mu.Lock()
+					added++
+					mu.Unlock()
+				}
+			}(i)
+		}
+		wg.Wait()
+
+		assert.Equal(t, limit, added)
+		count, err := s.CountRange(ctx, "key", "min", "+inf")
+		assert.NoError(t, err)
+		assert.Equal(t, int64(limit), count)
+	})
+}
+
+func fastForward(t *testing.T, b Backend, d time.Duration) {
+	t.Helper()
+	if b.FastForward != nil {
+		b.FastForward(t, d)
+		return
+	}
+	time.Sleep(d)
+}
@@ -0,0 +1,71 @@
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ExpiredKeysRemover is implemented by the storages that have no native key
+// expiry. RemoveExpired deletes the keys whose TTL has passed and returns how
+// many were removed.
+type ExpiredKeysRemover interface {
+	RemoveExpired(ctx context.Context) (int, error)
+}
+
+// Sweeper periodically removes the expired keys of the storages without
+// native TTL, so the keys of one-off clients do not accumulate.
+type Sweeper struct {
+	Storages []ExpiredKeysRemover
+	Interval time.Duration
+	// OnError receives the errors of the sweeps run by Run.
+	OnError func(error)
+}
+
+func NewSweeper(interval time.Duration, storages ...ExpiredKeysRemover) *Sweeper {
+	return &Sweeper{
+		Storages: storages,
+		Interval: interval,
+	}
+}
+
+// Sweep removes the expired keys of every storage once.
+func (s *Sweeper) Sweep(ctx context.Context) (int, error) {
+	var errs []error
+	removed := 0
+
+	for _, storage := range s.Storages {
+		n, err := storage.RemoveExpired(ctx)
+		if err != nil {
+			errs = append(errs, err)
+		}
+		removed += n
+	}
+
+	if err := errors.Join(errs...); err != nil {
+		return removed, fmt.Errorf("error when removing expired keys: %w", err)
+	}
+	return removed, nil
+}
+
+// Run sweeps the storages every Interval until ctx is done.
+func (s *Sweeper) Run(ctx context.Context) {
+	if s.Interval <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(s.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if _, err := s.Sweep(ctx); err != nil && s.OnError != nil {
+				s.OnError(err)
+			}
+		}
+	}
+}
@@ -0,0 +1,91 @@
+package ratelimit_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/GeovaneCavalcante/rate-limit-api/pkg/ratelimit"
+	"github.com/GeovaneCavalcante/rate-limit-api/pkg/ratelimit/clocktest"
+	"github.com/GeovaneCavalcante/rate-limit-api/pkg/ratelimit/memory"
+	"github.com/stretchr/testify/assert"
+)
+
+type failingRemover struct{}
+
+func (failingRemover) RemoveExpired(ctx context.Context) (int, error) {
+	return 0, errors.New("error")
+}
+
+func TestSweeper(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("should remove the keys of the limiter once the window expires", func(t *testing.T) {
+		clock := clocktest.NewFakeClock(time.Unix(1700000000, 0))
+		es := memory.NewMemoryEventStorage(0, memory.WithClock(clock))
+		rl, err := ratelimit.New(es, "ip", 5, time.Minute, ratelimit.WithClock(clock))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		for _, ip := range []string{"10.0.0.1", "10.0.0.2", "10.0.0.3"} {
+			_, err := rl.Decide(ctx, ip, nil)
+			assert.NoError(t, err)
+		}
+
+		sweeper := ratelimit.NewSweeper(time.Minute, es)
+
+		removed, err := sweeper.Sweep(ctx)
+		assert.NoError(t, err)
+		assert.Equal(t, 0, removed)
+
+		clock.Advance(time.Minute)
+
+		removed, err = sweeper.Sweep(ctx)
+		assert.NoError(t, err)
+		assert.Equal(t, 3, removed)
+	})
+
+	t.Run("should keep sweeping the storages when one fails", func(t *testing.T) {
+		clock := clocktest.NewFakeClock(time.Unix(1700000000, 0))
+		cs := memory.NewMemoryCounterStorage(0, memory.WithClock(clock))
+		_, _ = cs.IncrementCounter(ctx, "key", 1, time.Second)
+		clock.Advance(time.Second)
+
+		removed, err := ratelimit.NewSweeper(time.Minute, failingRemover{}, cs).Sweep(ctx)
+		assert.Equal(t, "error when removing expired keys: error", err.Error())
+		assert.Equal(t, 1, removed)
+	})
+
+	t.Run("should sweep every interval until the context is done", func(t *testing.T) {
+		clock := clocktest.NewFakeClock(time.Unix(1700000000, 0))
+		cs := memory.NewMemoryCounterStorage(0, memory.WithClock(clock))
+		_, _ = cs.IncrementCounter(ctx, "key", 1, time.Second)
+		clock.Advance(time.Second)
+
+		errs := make(chan error, 1)
+		sweeper := ratelimit.NewSweeper(time.Millisecond, failingRemover{}, cs)
+		sweeper.OnError = func(err error) {
+			select {
+			case errs <- err:
+			default:
+			}
+		}
+
+		ctx, cancel := context.WithCancel(ctx)
+		done := make(chan struct{})
+		go func() {
+			sweeper.Run(ctx)
+			close(done)
+		}()
+
+		assert.Error(t, <-errs)
+		removed, err := cs.RemoveExpired(ctx)
+		assert.NoError(t, err)
+		assert.Equal(t, 0, removed)
+
+		cancel()
+		<-done
+	})
+}
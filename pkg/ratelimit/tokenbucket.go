@@ -0,0 +1,119 @@
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"time"
+)
+
+type TokenBucketStorageInterface interface {
+	TakeToken(ctx context.Context, key string, capacity, cost int64, refillRate, now float64, ttl time.Duration) (float64, bool, error)
+}
+
+// TokenBucket is the state persisted for each key by a TokenBucketStorageInterface.
+// Tokens are refilled at refillRate tokens per second up to the bucket capacity.
+type TokenBucket struct {
+	Tokens    float64
+	UpdatedAt float64
+}
+
+func NewTokenBucket(capacity int64, now float64) *TokenBucket {
+	return &TokenBucket{
+		Tokens:    float64(capacity),
+		UpdatedAt: now,
+	}
+}
+
+// Take refills the bucket up to now and takes cost tokens when they are
+// available.
+func (b *TokenBucket) Take(capacity, cost int64, refillRate, now float64) bool {
+	elapsed := math.Max(0, now-b.UpdatedAt)
+	b.Tokens = math.Min(float64(capacity), b.Tokens+elapsed*refillRate)
+	b.UpdatedAt = now
+
+	if b.Tokens < float64(cost) {
+		return false
+	}
+
+	b.Tokens -= float64(cost)
+	return true
+}
+
+type TokenBucketLimiter struct {
+	BucketStorage TokenBucketStorageInterface
+	Clock         Clock
+	Options
+}
+
+func NewTokenBucketLimiter(bs TokenBucketStorageInterface, ns string, max int64, inter time.Duration, burst int64, opts ...Option) (*TokenBucketLimiter, error) {
+	s := newSettings(opts)
+
+	return &TokenBucketLimiter{
+		BucketStorage: bs,
+		Clock:         s.clock,
+		Options:       Options{NameSpace: ns, MaxInInterval: max, Interval: inter, Burst: burst},
+	}, nil
+}
+
+func (tb *TokenBucketLimiter) Limiter(ctx context.Context, key string, opt *Options) (bool, error) {
+	return limited(tb.Decide(ctx, key, opt))
+}
+
+func (tb *TokenBucketLimiter) Decide(ctx context.Context, key string, opt *Options) (*Decision, error) {
+
+	current := now(tb.Clock)
+
+	nameSpace := chooseString(tb.NameSpace, opt, func(o *Options) string { return o.NameSpace })
+	maxInInterval := chooseInt64(tb.MaxInInterval, opt, func(o *Options) int64 { return o.MaxInInterval })
+	interval := chooseDuration(tb.Interval, opt, func(o *Options) time.Duration { return o.Interval })
+	capacity := chooseInt64(tb.Burst, opt, func(o *Options) int64 { return o.Burst })
+	cost := requestCost(opt)
+
+	if maxInInterval <= 0 || interval <= 0 {
+		return nil, fmt.Errorf("invalid token bucket rate: %d requests in %s", maxInInterval, interval)
+	}
+	if capacity <= 0 {
+		capacity = maxInInterval
+	}
+
+	refillRate := float64(maxInInterval) / interval.Seconds()
+	ttl := secondsToDuration(float64(capacity) / refillRate)
+
+	bucketName := fmt.Sprintf("%s:%s", nameSpace, key)
+
+	if exceedsLimit(cost, capacity) {
+		return &Decision{
+			Limit:      capacity,
+			Window:     ttl,
+			ResetAt:    current.Add(ttl),
+			RetryAfter: ttl,
+			NameSpace:  nameSpace,
+			Key:        key,
+		}, nil
+	}
+
+	tokens, allowed, err := tb.BucketStorage.TakeToken(ctx, bucketName, capacity, cost, refillRate, float64(current.UnixNano())/float64(time.Second), ttl)
+	if err != nil {
+		return nil, fmt.Errorf("error when taking token: %w", err)
+	}
+
+	decision := &Decision{
+		Allowed:   allowed,
+		Limit:     capacity,
+		Window:    secondsToDuration(float64(capacity) / refillRate),
+		Remaining: int64(math.Floor(tokens)),
+		ResetAt:   current.Add(secondsToDuration((float64(capacity) - tokens) / refillRate)),
+		NameSpace: nameSpace,
+		Key:       key,
+	}
+	if !allowed {
+		decision.RetryAfter = secondsToDuration((float64(cost) - tokens) / refillRate)
+	}
+
+	return decision, nil
+}
+
+func secondsToDuration(seconds float64) time.Duration {
+	return time.Duration(math.Ceil(seconds * float64(time.Second)))
+}
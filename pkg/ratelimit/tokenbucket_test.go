@@ -0,0 +1,183 @@
+package ratelimit_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/GeovaneCavalcante/rate-limit-api/pkg/ratelimit"
+	"github.com/GeovaneCavalcante/rate-limit-api/pkg/ratelimit/clocktest"
+	"github.com/GeovaneCavalcante/rate-limit-api/pkg/ratelimit/memory"
+	mock_storage "github.com/GeovaneCavalcante/rate-limit-api/pkg/ratelimit/mock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+	"go.uber.org/mock/gomock"
+)
+
+type TokenBucketTestSuite struct {
+	suite.Suite
+	BucketStorageMock *mock_storage.MockTokenBucketStorageInterface
+}
+
+func (suite *TokenBucketTestSuite) SetupTest() {
+	ctrl := gomock.NewController(suite.T())
+	suite.BucketStorageMock = mock_storage.NewMockTokenBucketStorageInterface(ctrl)
+}
+
+func (suite *TokenBucketTestSuite) TestTake() {
+	suite.Run("should take tokens until the bucket is empty", func() {
+		b := ratelimit.NewTokenBucket(2, 100)
+
+		assert.True(suite.T(), b.Take(2, 1, 1, 100))
+		assert.True(suite.T(), b.Take(2, 1, 1, 100))
+		assert.False(suite.T(), b.Take(2, 1, 1, 100))
+	})
+
+	suite.Run("should refill tokens over time up to the capacity", func() {
+		b := ratelimit.NewTokenBucket(2, 100)
+		b.Tokens = 0
+
+		assert.False(suite.T(), b.Take(2, 1, 0.5, 101))
+		assert.True(suite.T(), b.Take(2, 1, 0.5, 102))
+		assert.Equal(suite.T(), float64(0), b.Tokens)
+
+		assert.True(suite.T(), b.Take(2, 1, 0.5, 1000))
+		assert.Equal(suite.T(), float64(1), b.Tokens)
+	})
+}
+
+func (suite *TokenBucketTestSuite) TestLimiter() {
+	suite.Run("should return false when a token is taken", func() {
+		suite.BucketStorageMock.EXPECT().TakeToken(gomock.Any(), "test:key", int64(10), int64(1), float64(10)/60, gomock.Any(), 60*time.Second).Return(float64(9), true, nil)
+
+		tb, err := ratelimit.NewTokenBucketLimiter(suite.BucketStorageMock, "test", 10, 60*time.Second, 0)
+		if err != nil {
+			suite.FailNow(err.Error())
+		}
+
+		value, err := tb.Limiter(context.Background(), "key", nil)
+		assert.NoError(suite.T(), err)
+		assert.False(suite.T(), value)
+	})
+
+	suite.Run("should return true when the bucket is empty", func() {
+		suite.BucketStorageMock.EXPECT().TakeToken(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(float64(0.5), false, nil)
+
+		tb, err := ratelimit.NewTokenBucketLimiter(suite.BucketStorageMock, "test", 10, 60*time.Second, 0)
+		if err != nil {
+			suite.FailNow(err.Error())
+		}
+
+		value, err := tb.Limiter(context.Background(), "key", nil)
+		assert.NoError(suite.T(), err)
+		assert.True(suite.T(), value)
+	})
+
+	suite.Run("should use the burst of the options as capacity", func() {
+		suite.BucketStorageMock.EXPECT().TakeToken(gomock.Any(), "token:key", int64(50), int64(1), float64(100000)/3600, gomock.Any(), 1800*time.Millisecond).Return(float64(49), true, nil)
+
+		tb, err := ratelimit.NewTokenBucketLimiter(suite.BucketStorageMock, "test", 0, 0, 0)
+		if err != nil {
+			suite.FailNow(err.Error())
+		}
+
+		value, err := tb.Limiter(context.Background(), "key", &ratelimit.Options{
+			NameSpace:     "token",
+			MaxInInterval: 100000,
+			Interval:      time.Hour,
+			Burst:         50,
+		})
+		assert.NoError(suite.T(), err)
+		assert.False(suite.T(), value)
+	})
+
+	suite.Run("should return an error when the rate is invalid", func() {
+		tb, err := ratelimit.NewTokenBucketLimiter(suite.BucketStorageMock, "test", 0, 0, 0)
+		if err != nil {
+			suite.FailNow(err.Error())
+		}
+
+		value, err := tb.Limiter(context.Background(), "key", nil)
+		assert.Equal(suite.T(), "invalid token bucket rate: 0 requests in 0s", err.Error())
+		assert.False(suite.T(), value)
+	})
+
+	suite.Run("should return an error when the storage fails", func() {
+		suite.BucketStorageMock.EXPECT().TakeToken(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(float64(0), false, errors.New("error"))
+
+		tb, err := ratelimit.NewTokenBucketLimiter(suite.BucketStorageMock, "test", 10, 60*time.Second, 0)
+		if err != nil {
+			suite.FailNow(err.Error())
+		}
+
+		value, err := tb.Limiter(context.Background(), "key", nil)
+		assert.Equal(suite.T(), "error when taking token: error", err.Error())
+		assert.False(suite.T(), value)
+	})
+}
+
+func (suite *TokenBucketTestSuite) TestDecide() {
+	suite.Run("should return the retry after when the bucket is empty", func() {
+		suite.BucketStorageMock.EXPECT().TakeToken(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(float64(0.5), false, nil)
+
+		tb, err := ratelimit.NewTokenBucketLimiter(suite.BucketStorageMock, "test", 10, 10*time.Second, 4)
+		if err != nil {
+			suite.FailNow(err.Error())
+		}
+
+		decision, err := tb.Decide(context.Background(), "key", nil)
+		assert.NoError(suite.T(), err)
+		assert.False(suite.T(), decision.Allowed)
+		assert.Equal(suite.T(), int64(4), decision.Limit)
+		assert.Equal(suite.T(), int64(0), decision.Remaining)
+		assert.Equal(suite.T(), 500*time.Millisecond, decision.RetryAfter)
+		assert.WithinDuration(suite.T(), time.Now().Add(3500*time.Millisecond), decision.ResetAt, 100*time.Millisecond)
+	})
+
+	suite.Run("should return the remaining tokens when a token is taken", func() {
+		suite.BucketStorageMock.EXPECT().TakeToken(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(float64(2.7), true, nil)
+
+		tb, err := ratelimit.NewTokenBucketLimiter(suite.BucketStorageMock, "test", 10, 10*time.Second, 4)
+		if err != nil {
+			suite.FailNow(err.Error())
+		}
+
+		decision, err := tb.Decide(context.Background(), "key", nil)
+		assert.NoError(suite.T(), err)
+		assert.True(suite.T(), decision.Allowed)
+		assert.Equal(suite.T(), int64(2), decision.Remaining)
+		assert.Equal(suite.T(), time.Duration(0), decision.RetryAfter)
+	})
+}
+
+func (suite *TokenBucketTestSuite) TestRefill() {
+	suite.Run("should refill one token per emission interval", func() {
+		clock := clocktest.NewFakeClock(time.Unix(1700000000, 0))
+		tb, err := ratelimit.NewTokenBucketLimiter(memory.NewMemoryTokenBucketStorage(0, memory.WithClock(clock)), "test", 2, 10*time.Second, 0, ratelimit.WithClock(clock))
+		if err != nil {
+			suite.FailNow(err.Error())
+		}
+
+		for i := 0; i < 2; i++ {
+			value, err := tb.Limiter(context.Background(), "key", nil)
+			assert.NoError(suite.T(), err)
+			assert.False(suite.T(), value)
+		}
+
+		clock.Advance(4 * time.Second)
+		decision, err := tb.Decide(context.Background(), "key", nil)
+		assert.NoError(suite.T(), err)
+		assert.False(suite.T(), decision.Allowed)
+		assert.Equal(suite.T(), time.Second, decision.RetryAfter)
+
+		clock.Advance(time.Second)
+		decision, err = tb.Decide(context.Background(), "key", nil)
+		assert.NoError(suite.T(), err)
+		assert.True(suite.T(), decision.Allowed)
+	})
+}
+
+func TestTokenBucketSuite(t *testing.T) {
+	suite.Run(t, new(TokenBucketTestSuite))
+}